@@ -1,3 +1,3 @@
 module github.com/jlegrone/xbargo
 
-go 1.17
+go 1.20
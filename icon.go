@@ -0,0 +1,259 @@
+package xbargo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	_ "image/jpeg" // register JPEG decoding for ResizeIcon
+	"image/png"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IconSize is the recommended width and height of icons, in points.
+const IconSize = 16
+
+// NewIconFromFile reads a PNG or JPEG image from the given path and resizes it
+// to IconSize, preserving aspect ratio.
+//
+// If the file name contains "@2x", e.g. "icon@2x.png", the icon is rendered at
+// double pixel density for retina displays as with ResizeIconRetina.
+func NewIconFromFile(path string) (io.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.Contains(filepath.Base(path), "@2x") {
+		return ResizeIconRetina(f, IconSize, IconSize)
+	}
+	return ResizeIcon(f, IconSize, IconSize)
+}
+
+// iconURLTimeout bounds NewIconFromURL requests whose context has no deadline.
+const iconURLTimeout = 10 * time.Second
+
+// MaxIconURLSize is the largest response body, in bytes, accepted by
+// NewIconFromURL. It leaves room for logos that are scaled down with
+// ResizeIcon afterwards, while stopping a wrong URL or a misbehaving server
+// from loading an arbitrarily large body into memory.
+const MaxIconURLSize = 1 << 20
+
+// supportedIconTypes are the image content types accepted by NewIconFromURL.
+var supportedIconTypes = map[string]bool{
+	"image/png":                true,
+	"image/jpeg":               true,
+	"image/gif":                true,
+	"image/tiff":               true,
+	"image/bmp":                true,
+	"image/webp":               true,
+	"image/heic":               true,
+	"image/x-icon":             true,
+	"image/vnd.microsoft.icon": true,
+}
+
+// NewIconFromURL downloads an icon, e.g. a service's favicon or logo.
+//
+// The response must have an image content type supported by macOS, which is
+// checked before the body is read, and a body of at most MaxIconURLSize bytes.
+// The whole body is read into memory before returning. If ctx has no deadline,
+// the request times out after 10 seconds.
+func NewIconFromURL(ctx context.Context, url string) (io.Reader, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, iconURLTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching icon from %s: unexpected status %s", url, resp.Status)
+	}
+	if resp.ContentLength > MaxIconURLSize {
+		return nil, fmt.Errorf("fetching icon from %s: body exceeds %d bytes", url, MaxIconURLSize)
+	}
+	// Read one byte past the limit to tell a body of exactly MaxIconURLSize
+	// bytes from a longer one.
+	body := io.LimitReader(resp.Body, MaxIconURLSize+1)
+	contentType := resp.Header.Get("Content-Type")
+	var sniffed []byte
+	if contentType == "" {
+		// Only the start of the body is needed to detect its content type.
+		sniffed = make([]byte, 512)
+		n, err := io.ReadFull(body, sniffed)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("fetching icon from %s: %w", url, err)
+		}
+		sniffed = sniffed[:n]
+		contentType = http.DetectContentType(sniffed)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !supportedIconTypes[mediaType] {
+		return nil, fmt.Errorf("fetching icon from %s: unsupported content type %q", url, contentType)
+	}
+	rest, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("fetching icon from %s: %w", url, err)
+	}
+	b := append(sniffed, rest...)
+	if len(b) > MaxIconURLSize {
+		return nil, fmt.Errorf("fetching icon from %s: body exceeds %d bytes", url, MaxIconURLSize)
+	}
+	return bytes.NewReader(b), nil
+}
+
+// ResizeIcon decodes a PNG or JPEG image and scales it to fit within w by h
+// pixels, preserving aspect ratio. The result is encoded as a PNG.
+func ResizeIcon(r io.Reader, w, h int) (io.Reader, error) {
+	img, err := resizeImage(r, w, h)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// ResizeIconRetina behaves like ResizeIcon, but scales the image to twice the
+// given logical size and marks it as 144 DPI so that macOS displays it at w by
+// h points with full detail on retina displays.
+func ResizeIconRetina(r io.Reader, w, h int) (io.Reader, error) {
+	img, err := resizeImage(r, 2*w, 2*h)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	b, err := setPNGDensity(buf.Bytes(), 144)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// MakeTemplateIcon decodes a PNG or JPEG image and converts it to a template
+// image suitable for use with Style.IconImageTemplate. Color information is
+// discarded by setting every pixel to black, while alpha is preserved so that
+// the image's shape is used as a mask.
+func MakeTemplateIcon(r io.Reader) (io.Reader, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding icon: %w", err)
+	}
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			a := color.NRGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA).A
+			dst.SetNRGBA(x, y, color.NRGBA{A: a})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+func resizeImage(r io.Reader, w, h int) (image.Image, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid icon size %dx%d", w, h)
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding icon: %w", err)
+	}
+	sb := src.Bounds()
+	if sb.Empty() {
+		return nil, errors.New("decoding icon: image is empty")
+	}
+	scale := math.Min(float64(w)/float64(sb.Dx()), float64(h)/float64(sb.Dy()))
+	dw := int(math.Max(1, math.Round(float64(sb.Dx())*scale)))
+	dh := int(math.Max(1, math.Round(float64(sb.Dy())*scale)))
+	return scaleImage(src, dw, dh), nil
+}
+
+// scaleImage resamples src to w by h pixels by averaging the source pixels
+// covered by each destination pixel, in premultiplied color space.
+func scaleImage(src image.Image, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := span(y, h, sb.Dy())
+		for x := 0; x < w; x++ {
+			x0, x1 := span(x, w, sb.Dx())
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sb.Min.X+sx, sb.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// span returns the range of source pixels covered by destination pixel i,
+// always including at least one source pixel.
+func span(i, dstLen, srcLen int) (int, int) {
+	start := i * srcLen / dstLen
+	end := (i + 1) * srcLen / dstLen
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// setPNGDensity inserts a pHYs chunk recording the given pixel density
+// immediately after the IHDR chunk of an encoded PNG.
+func setPNGDensity(b []byte, dpi float64) ([]byte, error) {
+	// The PNG signature is 8 bytes, followed by the 25 byte IHDR chunk.
+	const ihdrEnd = 8 + 25
+	if len(b) < ihdrEnd || string(b[12:16]) != "IHDR" {
+		return nil, errors.New("invalid PNG")
+	}
+	ppm := uint32(math.Round(dpi / 0.0254))
+	// A chunk is its data length, type, data, and a CRC of the type and data.
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk[0:4], 9)
+	copy(chunk[4:8], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:12], ppm)
+	binary.BigEndian.PutUint32(chunk[12:16], ppm)
+	chunk[16] = 1 // unit is the meter
+	binary.BigEndian.PutUint32(chunk[17:21], crc32.ChecksumIEEE(chunk[4:17]))
+
+	out := make([]byte, 0, len(b)+len(chunk))
+	out = append(out, b[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, b[ihdrEnd:]...), nil
+}
@@ -0,0 +1,252 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func encodeTestImage(t *testing.T, w, h int, encode func(io.Writer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 0xff, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, nil)
+}
+
+func decodeIconSize(t *testing.T, r io.Reader) image.Point {
+	t.Helper()
+	img, err := png.Decode(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.Bounds().Size()
+}
+
+func TestResizeIcon(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		src           []byte
+		width, height int
+		retina        bool
+		want          image.Point
+	}{
+		{"square", encodeTestImage(t, 64, 64, png.Encode), 16, 16, false, image.Pt(16, 16)},
+		{"wide", encodeTestImage(t, 64, 32, png.Encode), 16, 16, false, image.Pt(16, 8)},
+		{"tall", encodeTestImage(t, 30, 60, encodeJPEG), 16, 16, false, image.Pt(8, 16)},
+		{"upscale", encodeTestImage(t, 4, 4, png.Encode), 16, 16, false, image.Pt(16, 16)},
+		{"retina", encodeTestImage(t, 64, 32, png.Encode), 16, 16, true, image.Pt(32, 16)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resize := xbargo.ResizeIcon
+			if tc.retina {
+				resize = xbargo.ResizeIconRetina
+			}
+			icon, err := resize(bytes.NewReader(tc.src), tc.width, tc.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(icon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decodeIconSize(t, bytes.NewReader(b)); got != tc.want {
+				t.Errorf("got size %v, want %v", got, tc.want)
+			}
+			if hasDensity := bytes.Contains(b, []byte("pHYs")); hasDensity != tc.retina {
+				t.Errorf("got pHYs chunk %t, want %t", hasDensity, tc.retina)
+			}
+		})
+	}
+}
+
+func TestResizeIcon_invalid(t *testing.T) {
+	if _, err := xbargo.ResizeIcon(bytes.NewReader([]byte("not an image")), 16, 16); err == nil {
+		t.Error("expected error decoding invalid image")
+	}
+	if _, err := xbargo.ResizeIcon(bytes.NewReader(beakerImage), 0, 16); err == nil {
+		t.Error("expected error for zero width")
+	}
+}
+
+func TestNewIconFromFile(t *testing.T) {
+	dir := t.TempDir()
+	src := encodeTestImage(t, 128, 128, png.Encode)
+	for name, want := range map[string]image.Point{
+		"icon.png":    image.Pt(16, 16),
+		"icon@2x.png": image.Pt(32, 32),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, src, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			icon, err := xbargo.NewIconFromFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decodeIconSize(t, icon); got != want {
+				t.Errorf("got size %v, want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := xbargo.NewIconFromFile(filepath.Join(dir, "missing.png")); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want not exist", err)
+	}
+}
+
+func TestNewIconFromURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(beakerImage)
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/sniffed", func(w http.ResponseWriter, r *http.Request) {
+		// An empty Content-Type header stops net/http from setting one.
+		w.Header()["Content-Type"] = nil
+		w.Write(beakerImage)
+	})
+	mux.HandleFunc("/huge.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, xbargo.MaxIconURLSize+1))
+	})
+	mux.HandleFunc("/huge-chunked.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		for i := 0; i <= xbargo.MaxIconURLSize/1024; i++ {
+			w.Write(make([]byte, 1024))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/limit.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, xbargo.MaxIconURLSize))
+	})
+	mux.HandleFunc("/endless.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/slow.png", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, path := range []string{"/icon.png", "/sniffed"} {
+		t.Run(path, func(t *testing.T) {
+			icon, err := xbargo.NewIconFromURL(context.Background(), srv.URL+path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(icon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, beakerImage) {
+				t.Error("icon bytes do not match served image")
+			}
+		})
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		icon, err := xbargo.NewIconFromURL(context.Background(), srv.URL+"/limit.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := io.Copy(io.Discard, icon); n != xbargo.MaxIconURLSize {
+			t.Errorf("got %d bytes, want %d", n, xbargo.MaxIconURLSize)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		path string
+		want string
+	}{
+		{"not found", "/missing.png", "unexpected status 404 Not Found"},
+		{"not an image", "/page.html", `unsupported content type "text/html; charset=utf-8"`},
+		// The body never ends, so this only passes if it is not read.
+		{"not an image without reading", "/endless.html", `unsupported content type "text/html"`},
+		{"too large", "/huge.png", "body exceeds 1048576 bytes"},
+		{"too large without length", "/huge-chunked.png", "body exceeds 1048576 bytes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := xbargo.NewIconFromURL(context.Background(), srv.URL+tc.path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := xbargo.NewIconFromURL(ctx, srv.URL+"/slow.png")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestMakeTemplateIcon(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 0xff, G: 0x80, B: 0x10, A: 0xff})
+	src.SetNRGBA(1, 0, color.NRGBA{R: 0x20, G: 0xff, B: 0xff, A: 0x80})
+	src.SetNRGBA(2, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x00})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	icon, err := xbargo.MakeTemplateIcon(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(icon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(3, 1) {
+		t.Fatalf("got size %v, want 3x1", got)
+	}
+	for x, wantAlpha := range []uint8{0xff, 0x80, 0x00} {
+		got := color.NRGBAModel.Convert(img.At(x, 0)).(color.NRGBA)
+		if want := (color.NRGBA{A: wantAlpha}); got != want {
+			t.Errorf("pixel %d: got %v, want %v", x, got, want)
+		}
+	}
+
+	if _, err := xbargo.MakeTemplateIcon(bytes.NewReader([]byte("not an image"))); err == nil {
+		t.Error("expected error decoding invalid image")
+	}
+}
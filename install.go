@@ -0,0 +1,54 @@
+package xbargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PluginFilename returns the file name xbar uses to determine how often to
+// refresh a plugin, e.g. PluginFilename("myplugin.sh", 30*time.Second) returns
+// "myplugin.30s.sh".
+//
+// The interval is expressed in the largest unit of days, hours, minutes or
+// seconds that represents it exactly, so 90 seconds becomes "90s" rather than
+// being rounded to minutes. Intervals are rounded up to whole seconds.
+func PluginFilename(name string, interval time.Duration) string {
+	suffix := formatInterval(interval)
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), suffix, ext)
+	}
+	return fmt.Sprintf("%s.%s", name, suffix)
+}
+
+func formatInterval(interval time.Duration) string {
+	seconds := int64((interval + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	for _, unit := range []struct {
+		suffix  string
+		seconds int64
+	}{
+		{"d", 24 * 60 * 60},
+		{"h", 60 * 60},
+		{"m", 60},
+	} {
+		if seconds%unit.seconds == 0 {
+			return fmt.Sprintf("%d%s", seconds/unit.seconds, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// InstallPath returns the path that a plugin should be installed to in the
+// current user's xbar plugins directory, using PluginFilename.
+func InstallPath(name string, interval time.Duration) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Application Support", "xbar", "plugins", PluginFilename(name, interval)), nil
+}
@@ -0,0 +1,45 @@
+package xbargo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestPluginFilename(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+		want     string
+	}{
+		{"myplugin", 30 * time.Second, "myplugin.30s"},
+		{"myplugin.bin", 30 * time.Second, "myplugin.30s.bin"},
+		{"helloworld.sh", time.Minute, "helloworld.1m.sh"},
+		{"myplugin", 90 * time.Second, "myplugin.90s"},
+		{"myplugin", 90 * time.Minute, "myplugin.90m"},
+		{"myplugin", 2 * time.Hour, "myplugin.2h"},
+		{"myplugin", 36 * time.Hour, "myplugin.36h"},
+		{"myplugin", 48 * time.Hour, "myplugin.2d"},
+		{"myplugin", 1500 * time.Millisecond, "myplugin.2s"},
+		{"myplugin", 0, "myplugin.1s"},
+		{".hidden", time.Minute, ".hidden.1m"},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := xbargo.PluginFilename(tc.name, tc.interval); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInstallPath(t *testing.T) {
+	t.Setenv("HOME", "/Users/gopher")
+	got, err := xbargo.InstallPath("helloworld", 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/Users/gopher/Library/Application Support/xbar/plugins/helloworld.5m"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
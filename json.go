@@ -0,0 +1,236 @@
+package xbargo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var (
+	_ json.Marshaler   = &Plugin{}
+	_ json.Unmarshaler = &Plugin{}
+	_ json.Marshaler   = &MenuItem{}
+	_ json.Unmarshaler = &MenuItem{}
+)
+
+// Discriminators for the XbarElement and Action interfaces in JSON.
+const (
+	jsonTypeSeparator = "separator"
+	jsonTypeMenuItem  = "menuItem"
+	jsonTypeHref      = "href"
+	jsonTypeShell     = "shell"
+)
+
+type jsonPlugin struct {
+	Metadata    *Metadata     `json:",omitempty"`
+	Variables   []Variable    `json:",omitempty"`
+	Title       *MenuItem     `json:",omitempty"`
+	CycleTitles []*MenuItem   `json:",omitempty"`
+	Elements    []jsonElement `json:",omitempty"`
+	Strict      bool          `json:",omitempty"`
+	LegacyShell bool          `json:",omitempty"`
+	CleanOutput bool          `json:",omitempty"`
+	MaxSize     int           `json:",omitempty"`
+}
+
+type jsonElement struct {
+	Type     string
+	MenuItem *MenuItem `json:",omitempty"`
+}
+
+type jsonMenuItem struct {
+	Title    string            `json:",omitempty"`
+	Icon     []byte            `json:",omitempty"`
+	SFSymbol string            `json:",omitempty"`
+	Style    *Style            `json:",omitempty"`
+	Tooltip  string            `json:",omitempty"`
+	Shortcut string            `json:",omitempty"`
+	Action   *jsonAction       `json:",omitempty"`
+	Disabled bool              `json:",omitempty"`
+	Plain    bool              `json:",omitempty"`
+	Dropdown *bool             `json:",omitempty"`
+	Checked  bool              `json:",omitempty"`
+	Refresh  bool              `json:",omitempty"`
+	Alt      *MenuItem         `json:",omitempty"`
+	SubMenu  []*MenuItem       `json:",omitempty"`
+	Params   map[string]string `json:",omitempty"`
+	Hidden   bool              `json:",omitempty"`
+}
+
+type jsonAction struct {
+	Type         string
+	URI          string   `json:",omitempty"`
+	Command      string   `json:",omitempty"`
+	Args         []string `json:",omitempty"`
+	OpenTerminal bool     `json:",omitempty"`
+}
+
+// MarshalJSON encodes the plugin, including all of its elements, as JSON.
+//
+// Icons are read fully and encoded as base64. If an Icon implements io.Seeker,
+// its offset is restored afterwards so that it can still be rendered.
+func (p *Plugin) MarshalJSON() ([]byte, error) {
+	jp := jsonPlugin{
+		Metadata:    p.Metadata,
+		Variables:   p.Variables,
+		Title:       p.Title,
+		CycleTitles: p.CycleTitles,
+		Strict:      p.Strict,
+		LegacyShell: p.LegacyShell,
+		CleanOutput: p.CleanOutput,
+		MaxSize:     p.MaxSize,
+	}
+	for _, el := range p.Elements {
+		switch el := el.(type) {
+		case Separator:
+			jp.Elements = append(jp.Elements, jsonElement{Type: jsonTypeSeparator})
+		case *MenuItem:
+			jp.Elements = append(jp.Elements, jsonElement{Type: jsonTypeMenuItem, MenuItem: el})
+		default:
+			return nil, fmt.Errorf("unsupported element type %T", el)
+		}
+	}
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON decodes a plugin encoded by MarshalJSON.
+func (p *Plugin) UnmarshalJSON(b []byte) error {
+	var jp jsonPlugin
+	if err := json.Unmarshal(b, &jp); err != nil {
+		return err
+	}
+	*p = Plugin{
+		Metadata:    jp.Metadata,
+		Variables:   jp.Variables,
+		Title:       jp.Title,
+		CycleTitles: jp.CycleTitles,
+		Strict:      jp.Strict,
+		LegacyShell: jp.LegacyShell,
+		CleanOutput: jp.CleanOutput,
+		MaxSize:     jp.MaxSize,
+	}
+	for _, el := range jp.Elements {
+		switch el.Type {
+		case jsonTypeSeparator:
+			p.Elements = append(p.Elements, Separator{})
+		case jsonTypeMenuItem:
+			if el.MenuItem == nil {
+				return fmt.Errorf("element of type %q is missing MenuItem", el.Type)
+			}
+			p.Elements = append(p.Elements, el.MenuItem)
+		default:
+			return fmt.Errorf("unsupported element type %q", el.Type)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the menu item and its submenus as JSON.
+func (m *MenuItem) MarshalJSON() ([]byte, error) {
+	jm := jsonMenuItem{
+		Title:    m.Title,
+		SFSymbol: m.SFSymbol,
+		Tooltip:  m.Tooltip,
+		Shortcut: m.Shortcut,
+		Disabled: m.Disabled,
+		Plain:    m.Plain,
+		Dropdown: m.Dropdown,
+		Checked:  m.Checked,
+		Refresh:  m.Refresh,
+		Alt:      m.Alt,
+		SubMenu:  m.SubMenu,
+		Params:   m.Params,
+		Hidden:   m.Hidden,
+	}
+	if m.Style != (Style{}) {
+		jm.Style = &m.Style
+	}
+	if m.Icon != nil {
+		b, err := readIcon(m.Icon)
+		if err != nil {
+			return nil, fmt.Errorf("reading icon for menu item %q: %w", m.Title, err)
+		}
+		jm.Icon = b
+	}
+	switch action := m.Action.(type) {
+	case nil:
+	case HrefAction:
+		jm.Action = &jsonAction{Type: jsonTypeHref, URI: action.URI}
+	case ShellAction:
+		jm.Action = &jsonAction{
+			Type:         jsonTypeShell,
+			Command:      action.Command,
+			Args:         action.Args,
+			OpenTerminal: action.OpenTerminal,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported action type %T", action)
+	}
+	return json.Marshal(jm)
+}
+
+// UnmarshalJSON decodes a menu item encoded by MarshalJSON.
+func (m *MenuItem) UnmarshalJSON(b []byte) error {
+	var jm jsonMenuItem
+	if err := json.Unmarshal(b, &jm); err != nil {
+		return err
+	}
+	*m = MenuItem{
+		Title:    jm.Title,
+		SFSymbol: jm.SFSymbol,
+		Tooltip:  jm.Tooltip,
+		Shortcut: jm.Shortcut,
+		Disabled: jm.Disabled,
+		Plain:    jm.Plain,
+		Dropdown: jm.Dropdown,
+		Checked:  jm.Checked,
+		Refresh:  jm.Refresh,
+		Alt:      jm.Alt,
+		SubMenu:  jm.SubMenu,
+		Params:   jm.Params,
+		Hidden:   jm.Hidden,
+	}
+	if jm.Style != nil {
+		m.Style = *jm.Style
+	}
+	if jm.Icon != nil {
+		m.Icon = bytes.NewReader(jm.Icon)
+	}
+	if jm.Action != nil {
+		switch jm.Action.Type {
+		case jsonTypeHref:
+			m.Action = NewHrefAction(jm.Action.URI)
+		case jsonTypeShell:
+			m.Action = ShellAction{
+				Command:      jm.Action.Command,
+				Args:         jm.Action.Args,
+				OpenTerminal: jm.Action.OpenTerminal,
+			}
+		default:
+			return fmt.Errorf("unsupported action type %q", jm.Action.Type)
+		}
+	}
+	return nil
+}
+
+// readIcon reads all of r, restoring its offset afterwards if it is an
+// io.Seeker.
+func readIcon(r io.Reader) ([]byte, error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return io.ReadAll(r)
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,87 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestPlugin_JSON_roundTrip(t *testing.T) {
+	newPlugin := func() *xbargo.Plugin {
+		return xbargo.NewPlugin().
+			WithMetadata(xbargo.Metadata{Title: "Complex", Dependencies: []string{"go"}}).
+			WithVariables(xbargo.Variable{Name: "VAR_NAME", Default: "x", Description: "A variable"}).
+			WithTitles(
+				xbargo.NewMenuItem("one").WithIcon(bytes.NewReader(beakerImage)),
+				xbargo.NewMenuItem("two").WithDropdown(false),
+			).
+			WithElements(
+				xbargo.NewMenuItem("Link").WithHref("https://example.com").WithShortcut("l", xbargo.CommandKey),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("Parent").WithSubMenu(
+					xbargo.NewMenuItem("Shell").WithAction(
+						xbargo.NewShellAction("say", "it's", "me").WithTerminal(),
+					).WithRefresh().WithChecked(true),
+					xbargo.NewMenuItem("Styled").WithStyle(xbargo.Style{
+						Color: "red", ColorDark: "blue", Font: "Menlo", Size: 12, ANSI: true,
+					}).WithEmojize(false).WithTooltip("tip").WithDisabled(),
+				).WithAlt(xbargo.NewMenuItem("Alt").WithSFSymbol("cloud").WithPlain()),
+			).
+			WithLegacyShell()
+	}
+
+	b, err := json.Marshal(newPlugin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded xbargo.Plugin
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	reencoded, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reencoded, b) {
+		t.Errorf("re-encoded JSON mismatch\ngot:  %s\nwant: %s", reencoded, b)
+	}
+	// Compare with a fresh plugin, since rendering consumes icons.
+	want := render(t, newPlugin())
+	if got := render(t, &decoded); got != want {
+		t.Errorf("round trip mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlugin_MarshalJSON_iconReusable(t *testing.T) {
+	p := xbargo.NewPlugin().WithIcon(bytes.NewReader(beakerImage))
+	if _, err := json.Marshal(p); err != nil {
+		t.Fatal(err)
+	}
+	want := render(t, xbargo.NewPlugin().WithIcon(bytes.NewReader(beakerImage)))
+	if got := render(t, p); got != want {
+		t.Errorf("icon was consumed by MarshalJSON: got %q", got)
+	}
+}
+
+func TestPlugin_UnmarshalJSON_errors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		json string
+		want string
+	}{
+		{"element type", `{"Elements":[{"Type":"bogus"}]}`, `unsupported element type "bogus"`},
+		{"missing item", `{"Elements":[{"Type":"menuItem"}]}`, `element of type "menuItem" is missing MenuItem`},
+		{"action type", `{"Title":{"Action":{"Type":"bogus"}}}`, `unsupported action type "bogus"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var p xbargo.Plugin
+			err := json.Unmarshal([]byte(tc.json), &p)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
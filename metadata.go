@@ -0,0 +1,83 @@
+package xbargo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Metadata describes a plugin for the xbar app and the xbar-plugins repository.
+//
+// More information:
+// https://github.com/matryer/xbar-plugins/blob/main/CONTRIBUTING.md#metadata
+type Metadata struct {
+	// The title of the plugin.
+	Title string `json:",omitempty"`
+	// The version of the plugin, e.g. "v1.0".
+	Version string `json:",omitempty"`
+	// The name of the plugin author.
+	Author string `json:",omitempty"`
+	// The GitHub username of the plugin author.
+	AuthorGitHub string `json:",omitempty"`
+	// A short description of what the plugin does.
+	//
+	// Line breaks are replaced with spaces, since each metadata value must fit
+	// on a single line.
+	Desc string `json:",omitempty"`
+	// A URL to a screenshot of the plugin.
+	Image string `json:",omitempty"`
+	// Dependencies required to run the plugin, e.g. "go".
+	Dependencies []string `json:",omitempty"`
+	// A URL with more information about the plugin.
+	AboutURL string `json:",omitempty"`
+}
+
+// WriteHeader writes the plugin's Metadata and Variables as a header of
+// comments for the script that xbar runs, such as
+// examples/helloworld/helloworld.1m.sh.
+//
+// xbar reads metadata and variables from the source of the plugin rather than
+// from its output, so the header is not written by RunW or StreamW. Every line
+// of output before the first separator would otherwise be shown as a title.
+func (p *Plugin) WriteHeader(w io.Writer) error {
+	if p.Metadata != nil {
+		if err := p.Metadata.render(w); err != nil {
+			return err
+		}
+	}
+	for _, v := range p.Variables {
+		if err := v.render(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (md *Metadata) render(w io.Writer) error {
+	for _, tag := range []struct {
+		name  string
+		value string
+	}{
+		{"title", md.Title},
+		{"version", md.Version},
+		{"author", md.Author},
+		{"author.github", md.AuthorGitHub},
+		{"desc", md.Desc},
+		{"abouturl", md.AboutURL},
+		{"image", md.Image},
+		{"dependencies", strings.Join(md.Dependencies, ",")},
+	} {
+		if tag.value == "" {
+			continue
+		}
+		if err := printMetadataTag(w, tag.name, tag.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printMetadataTag(w io.Writer, name, value string) error {
+	_, err := fmt.Fprintf(w, "# <xbar.%s>%s</xbar.%s>\n", name, lineBreakReplacer.Replace(value), name)
+	return err
+}
@@ -0,0 +1,47 @@
+package xbargo_test
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+// Example of how to include plugin metadata for the xbar app and xbar-plugins repository.
+func ExamplePlugin_metadata() {
+	p := xbargo.NewPlugin().WithMetadata(xbargo.Metadata{
+		Title:        "Hello World",
+		Version:      "v1.0",
+		Author:       "Jacob LeGrone",
+		AuthorGitHub: "jlegrone",
+		Desc:         "Greet the current user.\nSays hello.",
+		AboutURL:     "https://github.com/jlegrone/xbargo",
+		Image:        "https://example.com/screenshot.png",
+		Dependencies: []string{"go"},
+	}).WithText("👋🌎")
+	// The header belongs in the script that runs the plugin, since xbar reads
+	// metadata from the plugin source rather than its output.
+	if err := p.WriteHeader(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// # <xbar.title>Hello World</xbar.title>
+	// # <xbar.version>v1.0</xbar.version>
+	// # <xbar.author>Jacob LeGrone</xbar.author>
+	// # <xbar.author.github>jlegrone</xbar.author.github>
+	// # <xbar.desc>Greet the current user. Says hello.</xbar.desc>
+	// # <xbar.abouturl>https://github.com/jlegrone/xbargo</xbar.abouturl>
+	// # <xbar.image>https://example.com/screenshot.png</xbar.image>
+	// # <xbar.dependencies>go</xbar.dependencies>
+}
+
+func TestPlugin_RunW_header(t *testing.T) {
+	p := xbargo.NewPlugin().
+		WithMetadata(xbargo.Metadata{Title: "Hello World"}).
+		WithVariables(xbargo.Variable{Name: "VAR_NAME", Default: "x"}).
+		WithText("Title")
+	if got, want := render(t, p), "Title| refresh=false trim=false\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
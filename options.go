@@ -0,0 +1,213 @@
+package xbargo
+
+import (
+	"image/color"
+	"io"
+)
+
+// MenuItemOption configures a MenuItem created by NewMenuItemOpts.
+//
+// Options are an alternative to the With methods on MenuItem that can be
+// collected into slices, e.g. to share configuration between items or to add
+// it conditionally. Each With method has a corresponding option.
+type MenuItemOption func(*MenuItem)
+
+// NewMenuItemOpts creates a menu item with the given title, applying opts in
+// order. It is equivalent to calling the corresponding With methods on the
+// result of NewMenuItem.
+func NewMenuItemOpts(title string, opts ...MenuItemOption) *MenuItem {
+	m := NewMenuItem(title)
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithStyleOpt is the option form of MenuItem.WithStyle.
+func WithStyleOpt(style Style) MenuItemOption {
+	return func(m *MenuItem) { m.WithStyle(style) }
+}
+
+// WithColorValueOpt is the option form of MenuItem.WithColorValue.
+func WithColorValueOpt(c color.Color) MenuItemOption {
+	return func(m *MenuItem) { m.WithColorValue(c) }
+}
+
+// WithBackgroundColorOpt is the option form of MenuItem.WithBackgroundColor.
+func WithBackgroundColorOpt(color string) MenuItemOption {
+	return func(m *MenuItem) { m.WithBackgroundColor(color) }
+}
+
+// WithBackgroundColorValueOpt is the option form of MenuItem.WithBackgroundColorValue.
+func WithBackgroundColorValueOpt(c color.Color) MenuItemOption {
+	return func(m *MenuItem) { m.WithBackgroundColorValue(c) }
+}
+
+// WithColorsOpt is the option form of MenuItem.WithColors.
+func WithColorsOpt(light, dark string) MenuItemOption {
+	return func(m *MenuItem) { m.WithColors(light, dark) }
+}
+
+// WithANSIOpt is the option form of MenuItem.WithANSI.
+func WithANSIOpt() MenuItemOption {
+	return func(m *MenuItem) { m.WithANSI() }
+}
+
+// WithEmojizeOpt is the option form of MenuItem.WithEmojize.
+func WithEmojizeOpt(emojize bool) MenuItemOption {
+	return func(m *MenuItem) { m.WithEmojize(emojize) }
+}
+
+// WithRefreshOpt is the option form of MenuItem.WithRefresh.
+func WithRefreshOpt() MenuItemOption {
+	return func(m *MenuItem) { m.WithRefresh() }
+}
+
+// WithDropdownOpt is the option form of MenuItem.WithDropdown.
+func WithDropdownOpt(dropdown bool) MenuItemOption {
+	return func(m *MenuItem) { m.WithDropdown(dropdown) }
+}
+
+// WithCheckedOpt is the option form of MenuItem.WithChecked.
+func WithCheckedOpt(checked bool) MenuItemOption {
+	return func(m *MenuItem) { m.WithChecked(checked) }
+}
+
+// WithDisabledOpt is the option form of MenuItem.WithDisabled.
+func WithDisabledOpt() MenuItemOption {
+	return func(m *MenuItem) { m.WithDisabled() }
+}
+
+// WithPlainOpt is the option form of MenuItem.WithPlain.
+func WithPlainOpt() MenuItemOption {
+	return func(m *MenuItem) { m.WithPlain() }
+}
+
+// WithActionOpt is the option form of MenuItem.WithAction.
+func WithActionOpt(action Action) MenuItemOption {
+	return func(m *MenuItem) { m.WithAction(action) }
+}
+
+// WithHrefOpt is the option form of MenuItem.WithHref.
+func WithHrefOpt(uri string) MenuItemOption {
+	return func(m *MenuItem) { m.WithHref(uri) }
+}
+
+// WithShellOpt is the option form of MenuItem.WithShell.
+func WithShellOpt(command string, args ...string) MenuItemOption {
+	return func(m *MenuItem) { m.WithShell(command, args...) }
+}
+
+// WithIconOpt is the option form of MenuItem.WithIcon.
+func WithIconOpt(icon io.Reader) MenuItemOption {
+	return func(m *MenuItem) { m.WithIcon(icon) }
+}
+
+// WithSFSymbolOpt is the option form of MenuItem.WithSFSymbol.
+func WithSFSymbolOpt(name string) MenuItemOption {
+	return func(m *MenuItem) { m.WithSFSymbol(name) }
+}
+
+// WithAltOpt is the option form of MenuItem.WithAlt.
+func WithAltOpt(item *MenuItem) MenuItemOption {
+	return func(m *MenuItem) { m.WithAlt(item) }
+}
+
+// WithTooltipOpt is the option form of MenuItem.WithTooltip.
+func WithTooltipOpt(tooltip string) MenuItemOption {
+	return func(m *MenuItem) { m.WithTooltip(tooltip) }
+}
+
+// WithSubMenuOpt is the option form of MenuItem.WithSubMenu.
+func WithSubMenuOpt(items ...*MenuItem) MenuItemOption {
+	return func(m *MenuItem) { m.WithSubMenu(items...) }
+}
+
+// WithParamsOpt is the option form of MenuItem.WithParams.
+func WithParamsOpt(params map[string]string) MenuItemOption {
+	return func(m *MenuItem) { m.WithParams(params) }
+}
+
+// WithShortcutOpt is the option form of MenuItem.WithShortcut.
+func WithShortcutOpt(key string, modifiers ...ModifierKey) MenuItemOption {
+	return func(m *MenuItem) { m.WithShortcut(key, modifiers...) }
+}
+
+// WhenOpt is the option form of MenuItem.When.
+func WhenOpt(cond bool) MenuItemOption {
+	return func(m *MenuItem) { m.When(cond) }
+}
+
+// PluginOption configures a Plugin created by NewPluginOpts. Each With method
+// on Plugin has a corresponding option.
+type PluginOption func(*Plugin)
+
+// NewPluginOpts creates a plugin, applying opts in order. It is equivalent to
+// calling the corresponding With methods on the result of NewPlugin.
+func NewPluginOpts(opts ...PluginOption) *Plugin {
+	p := NewPlugin()
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithMetadataOpt is the option form of Plugin.WithMetadata.
+func WithMetadataOpt(metadata Metadata) PluginOption {
+	return func(p *Plugin) { p.WithMetadata(metadata) }
+}
+
+// WithVariablesOpt is the option form of Plugin.WithVariables.
+func WithVariablesOpt(variables ...Variable) PluginOption {
+	return func(p *Plugin) { p.WithVariables(variables...) }
+}
+
+// WithTitlesOpt is the option form of Plugin.WithTitles.
+func WithTitlesOpt(items ...*MenuItem) PluginOption {
+	return func(p *Plugin) { p.WithTitles(items...) }
+}
+
+// WithTitleIconOpt is the option form of Plugin.WithIcon.
+func WithTitleIconOpt(icon io.Reader) PluginOption {
+	return func(p *Plugin) { p.WithIcon(icon) }
+}
+
+// WithTitleSFSymbolOpt is the option form of Plugin.WithSFSymbol.
+func WithTitleSFSymbolOpt(name string) PluginOption {
+	return func(p *Plugin) { p.WithSFSymbol(name) }
+}
+
+// WithTextOpt is the option form of Plugin.WithText.
+func WithTextOpt(title string) PluginOption {
+	return func(p *Plugin) { p.WithText(title) }
+}
+
+// WithLegacyShellOpt is the option form of Plugin.WithLegacyShell.
+func WithLegacyShellOpt() PluginOption {
+	return func(p *Plugin) { p.WithLegacyShell() }
+}
+
+// WithStrictOpt is the option form of Plugin.WithStrict.
+func WithStrictOpt() PluginOption {
+	return func(p *Plugin) { p.WithStrict() }
+}
+
+// WithCleanOutputOpt is the option form of Plugin.WithCleanOutput.
+func WithCleanOutputOpt() PluginOption {
+	return func(p *Plugin) { p.WithCleanOutput() }
+}
+
+// WithMaxSizeOpt is the option form of Plugin.WithMaxSize.
+func WithMaxSizeOpt(n int) PluginOption {
+	return func(p *Plugin) { p.WithMaxSize(n) }
+}
+
+// WithStatusRollupOpt is the option form of Plugin.WithStatusRollup.
+func WithStatusRollupOpt(levels ...StatusLevel) PluginOption {
+	return func(p *Plugin) { p.WithStatusRollup(levels...) }
+}
+
+// WithElementsOpt is the option form of Plugin.WithElements.
+func WithElementsOpt(elements ...XbarElement) PluginOption {
+	return func(p *Plugin) { p.WithElements(elements...) }
+}
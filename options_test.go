@@ -0,0 +1,108 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestNewMenuItemOpts(t *testing.T) {
+	builder := xbargo.NewPlugin().WithText("Title").WithStrict().WithElements(
+		xbargo.NewMenuItem("Item").
+			WithStyle(xbargo.Style{Color: "red"}).
+			WithAction(xbargo.NewHrefAction("https://example.com")).
+			WithTooltip("Tip").
+			WithShortcut("k", xbargo.CommandKey).
+			WithChecked(true).
+			WithAlt(xbargo.NewMenuItem("Alt")).
+			WithSubMenu(xbargo.NewMenuItem("Child").WithRefresh(), xbargo.NewMenuItem("Disabled").WithDisabled()),
+	)
+
+	// Options can be assembled into slices and shared between items.
+	common := []xbargo.MenuItemOption{
+		xbargo.WithStyleOpt(xbargo.Style{Color: "red"}),
+		xbargo.WithTooltipOpt("Tip"),
+	}
+	opts := append(common,
+		xbargo.WithActionOpt(xbargo.NewHrefAction("https://example.com")),
+		xbargo.WithShortcutOpt("k", xbargo.CommandKey),
+		xbargo.WithCheckedOpt(true),
+		xbargo.WithAltOpt(xbargo.NewMenuItemOpts("Alt")),
+		xbargo.WithSubMenuOpt(
+			xbargo.NewMenuItemOpts("Child", xbargo.WithRefreshOpt()),
+			xbargo.NewMenuItemOpts("Disabled", xbargo.WithDisabledOpt()),
+		),
+	)
+	options := xbargo.NewPluginOpts(
+		xbargo.WithTextOpt("Title"),
+		xbargo.WithStrictOpt(),
+		xbargo.WithElementsOpt(xbargo.NewMenuItemOpts("Item", opts...)),
+	)
+
+	if got, want := render(t, options), render(t, builder); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNewMenuItemOpts_all(t *testing.T) {
+	green := color.RGBA{G: 0xff, A: 0xff}
+	builder := xbargo.NewPlugin().
+		WithMetadata(xbargo.Metadata{Title: "Options"}).
+		WithVariables(xbargo.Variable{Type: xbargo.VariableTypeString, Name: "VAR_NAME", Default: "x"}).
+		WithText("Title").
+		WithSFSymbol("star").
+		WithTitles(xbargo.NewMenuItem("Cycle")).
+		WithLegacyShell().
+		WithCleanOutput().
+		WithMaxSize(1<<20).
+		WithElements(
+			xbargo.NewMenuItem("Href").WithHref("https://example.com").WithColors("black", "white"),
+			xbargo.NewMenuItem("Shell").WithShell("say", "hi").WithColorValue(green),
+			xbargo.NewMenuItem("Plain").WithPlain().WithBackgroundColor("red"),
+			xbargo.NewMenuItem("Value").WithBackgroundColorValue(green).WithANSI(),
+			xbargo.NewMenuItem(":smile:").WithEmojize(false).WithDropdown(false),
+			xbargo.NewMenuItem("Symbol").WithSFSymbol("gear").WithParams(map[string]string{"foo": "bar"}),
+			xbargo.NewMenuItem("Icon").WithIcon(bytes.NewReader(beakerImage)),
+			xbargo.NewMenuItem("Hidden").When(false),
+		)
+	options := xbargo.NewPluginOpts(
+		xbargo.WithMetadataOpt(xbargo.Metadata{Title: "Options"}),
+		xbargo.WithVariablesOpt(xbargo.Variable{Type: xbargo.VariableTypeString, Name: "VAR_NAME", Default: "x"}),
+		xbargo.WithTextOpt("Title"),
+		xbargo.WithTitleSFSymbolOpt("star"),
+		xbargo.WithTitlesOpt(xbargo.NewMenuItemOpts("Cycle")),
+		xbargo.WithLegacyShellOpt(),
+		xbargo.WithCleanOutputOpt(),
+		xbargo.WithMaxSizeOpt(1<<20),
+		xbargo.WithElementsOpt(
+			xbargo.NewMenuItemOpts("Href", xbargo.WithHrefOpt("https://example.com"), xbargo.WithColorsOpt("black", "white")),
+			xbargo.NewMenuItemOpts("Shell", xbargo.WithShellOpt("say", "hi"), xbargo.WithColorValueOpt(green)),
+			xbargo.NewMenuItemOpts("Plain", xbargo.WithPlainOpt(), xbargo.WithBackgroundColorOpt("red")),
+			xbargo.NewMenuItemOpts("Value", xbargo.WithBackgroundColorValueOpt(green), xbargo.WithANSIOpt()),
+			xbargo.NewMenuItemOpts(":smile:", xbargo.WithEmojizeOpt(false), xbargo.WithDropdownOpt(false)),
+			xbargo.NewMenuItemOpts("Symbol", xbargo.WithSFSymbolOpt("gear"), xbargo.WithParamsOpt(map[string]string{"foo": "bar"})),
+			xbargo.NewMenuItemOpts("Icon", xbargo.WithIconOpt(bytes.NewReader(beakerImage))),
+			xbargo.NewMenuItemOpts("Hidden", xbargo.WhenOpt(false)),
+		),
+	)
+
+	if got, want := renderScript(t, options), renderScript(t, builder); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNewPluginOpts_icons(t *testing.T) {
+	builder := xbargo.NewPlugin().
+		WithIcon(bytes.NewReader(beakerImage)).
+		WithStatusRollup(xbargo.StatusAvailable, xbargo.StatusPartially)
+	options := xbargo.NewPluginOpts(
+		xbargo.WithTitleIconOpt(bytes.NewReader(beakerImage)),
+		xbargo.WithStatusRollupOpt(xbargo.StatusAvailable, xbargo.StatusPartially),
+	)
+
+	if got, want := render(t, options), render(t, builder); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
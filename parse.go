@@ -0,0 +1,391 @@
+package xbargo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	metadataTagPattern = regexp.MustCompile(`^# <xbar\.([a-z.]+)>(.*)</xbar\.[a-z.]+>$`)
+	variablePattern    = regexp.MustCompile(`^(\w+)\((\w+)=(.*?)\): (.*)$`)
+	selectOptsPattern  = regexp.MustCompile(`^(.*) \[(.*)\]$`)
+)
+
+// Parse reads xbar plugin output, such as the output of RunW, and
+// reconstructs the Plugin that rendered it.
+//
+// The output may be preceded by the header of the plugin's script, as written
+// by WriteHeader, including a #! line. Metadata is only read from the header,
+// which ends at the first line that is not a metadata comment.
+//
+// This can be used to migrate existing xbar plugins to Go. Parameters that
+// xbargo does not support are kept in MenuItem.Params, and reported by
+// returning the parsed Plugin along with an error describing them.
+func Parse(r io.Reader) (*Plugin, error) {
+	p := &Plugin{}
+	var (
+		titles  []*MenuItem
+		parents []*MenuItem
+		inBody  bool
+		errs    []error
+		lineNum int
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		if !inBody && len(titles) == 0 {
+			if lineNum == 1 && strings.HasPrefix(line, "#!") {
+				continue
+			}
+			if ok, err := p.parseHeader(line); ok {
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				continue
+			}
+		}
+		if line == "---" {
+			if inBody {
+				p.Elements = append(p.Elements, Separator{})
+			}
+			inBody = true
+			parents = nil
+			continue
+		}
+		if !inBody {
+			item, alt, err := parseMenuItem(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			if alt {
+				return nil, fmt.Errorf("line %d: plugin titles may not have alternates", lineNum)
+			}
+			errs = append(errs, lineErrors(lineNum, item.unknownParams)...)
+			p.LegacyShell = p.LegacyShell || item.legacyShell
+			titles = append(titles, item.MenuItem)
+			continue
+		}
+
+		level := 0
+		for strings.HasPrefix(line[2*level:], "--") {
+			level++
+		}
+		rest := line[2*level:]
+		if strings.HasPrefix(rest, "-") && strings.TrimLeft(rest, "-") == "" {
+			return nil, fmt.Errorf("line %d: separators are not supported in submenus", lineNum)
+		}
+		item, alt, err := parseMenuItem(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		errs = append(errs, lineErrors(lineNum, item.unknownParams)...)
+		p.LegacyShell = p.LegacyShell || item.legacyShell
+		if level > len(parents) {
+			return nil, fmt.Errorf("line %d: unexpected nesting level %d", lineNum, level)
+		}
+		if alt {
+			// Alternates follow the children of the item they replace, and
+			// are followed by their own children.
+			if level >= len(parents) {
+				return nil, fmt.Errorf("line %d: alternate item has no preceding item", lineNum)
+			}
+			parents[level].Alt = item.MenuItem
+			parents = append(parents[:level], item.MenuItem)
+			continue
+		}
+		parents = append(parents[:level], item.MenuItem)
+		if level == 0 {
+			p.Elements = append(p.Elements, item.MenuItem)
+		} else {
+			parents[level-1].SubMenu = append(parents[level-1].SubMenu, item.MenuItem)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(titles) == 0 {
+		return nil, errors.New("missing plugin title")
+	}
+	p.Title = titles[0]
+	p.CycleTitles = titles[1:]
+	return p, errors.Join(errs...)
+}
+
+func lineErrors(lineNum int, unknownParams []string) []error {
+	var errs []error
+	for _, name := range unknownParams {
+		errs = append(errs, fmt.Errorf("line %d: unsupported parameter %q", lineNum, name))
+	}
+	return errs
+}
+
+// parseHeader parses metadata and variable declarations, reporting whether
+// line was a header line.
+func (p *Plugin) parseHeader(line string) (bool, error) {
+	match := metadataTagPattern.FindStringSubmatch(line)
+	if match == nil {
+		return false, nil
+	}
+	name, value := match[1], match[2]
+	if name == "var" {
+		v, err := parseVariable(value)
+		if err != nil {
+			return true, err
+		}
+		p.Variables = append(p.Variables, v)
+		return true, nil
+	}
+	if p.Metadata == nil {
+		p.Metadata = &Metadata{}
+	}
+	switch name {
+	case "title":
+		p.Metadata.Title = value
+	case "version":
+		p.Metadata.Version = value
+	case "author":
+		p.Metadata.Author = value
+	case "author.github":
+		p.Metadata.AuthorGitHub = value
+	case "desc":
+		p.Metadata.Desc = value
+	case "abouturl":
+		p.Metadata.AboutURL = value
+	case "image":
+		p.Metadata.Image = value
+	case "dependencies":
+		p.Metadata.Dependencies = strings.Split(value, ",")
+	default:
+		return true, fmt.Errorf("unsupported metadata tag %q", name)
+	}
+	return true, nil
+}
+
+func parseVariable(declaration string) (Variable, error) {
+	match := variablePattern.FindStringSubmatch(declaration)
+	if match == nil {
+		return Variable{}, fmt.Errorf("invalid variable declaration %q", declaration)
+	}
+	v := Variable{
+		Type:        VariableType(match[1]),
+		Name:        match[2],
+		Default:     match[3],
+		Description: match[4],
+	}
+	if unquoted, err := strconv.Unquote(v.Default); err == nil {
+		v.Default = unquoted
+	}
+	if v.Type == VariableTypeString {
+		// String is the default type, so it is left unset to match Variables
+		// declared without a Type.
+		v.Type = ""
+	}
+	if v.Type == VariableTypeSelect {
+		if opts := selectOptsPattern.FindStringSubmatch(v.Description); opts != nil {
+			v.Description = opts[1]
+			v.Options = strings.Split(opts[2], ", ")
+		}
+	}
+	return v, nil
+}
+
+// parsedMenuItem is a MenuItem along with any parameters that could not be
+// parsed into it.
+type parsedMenuItem struct {
+	*MenuItem
+	unknownParams []string
+	legacyShell   bool
+}
+
+// parseMenuItem parses a single line of output without its nesting prefix,
+// reporting whether the item is an alternate.
+func parseMenuItem(line string) (parsedMenuItem, bool, error) {
+	title, rawParams, _ := strings.Cut(line, "|")
+	item := parsedMenuItem{MenuItem: NewMenuItem(title)}
+	// xbar trims titles unless told otherwise.
+	item.Style.Trim = true
+	params, err := parseParams(rawParams)
+	if err != nil {
+		return item, false, err
+	}
+
+	var (
+		alt   bool
+		shell *ShellAction
+		// xbar opens a terminal unless told otherwise.
+		terminal = true
+		args     = map[int]string{}
+	)
+	for _, param := range params {
+		var err error
+		switch param.name {
+		case "key":
+			item.Shortcut = param.value
+		case "length":
+			var n uint64
+			n, err = strconv.ParseUint(param.value, 10, 0)
+			item.Style.MaxLength = uint(n)
+		case "color":
+			item.Style.Color, item.Style.ColorDark, _ = strings.Cut(param.value, ",")
+		case "bgcolor":
+			item.Style.BackgroundColor = param.value
+		case "font":
+			item.Style.Font = param.value
+		case "size":
+			var n uint64
+			n, err = strconv.ParseUint(param.value, 10, 0)
+			item.Style.Size = uint(n)
+		case "ansi":
+			item.Style.ANSI, err = strconv.ParseBool(param.value)
+		case "emojize":
+			var emojize bool
+			emojize, err = strconv.ParseBool(param.value)
+			item.Style.Emojize = &emojize
+		case "md":
+			item.Style.Markdown, err = strconv.ParseBool(param.value)
+		case "tooltip":
+			item.Tooltip = param.value
+		case "href":
+			item.Action = NewHrefAction(param.value)
+		case "shell", "bash":
+			shell = &ShellAction{Command: param.value}
+			item.legacyShell = param.name == "bash"
+		case "terminal":
+			terminal, err = strconv.ParseBool(param.value)
+		case "sfimage":
+			item.SFSymbol = param.value
+		case "image", "templateImage":
+			var b []byte
+			b, err = base64.StdEncoding.DecodeString(param.value)
+			item.Icon = bytes.NewReader(b)
+			item.Style.IconImageTemplate = param.name == "templateImage"
+		case "dropdown":
+			var dropdown bool
+			dropdown, err = strconv.ParseBool(param.value)
+			item.Dropdown = &dropdown
+		case "checked":
+			item.Checked, err = strconv.ParseBool(param.value)
+		case "disabled":
+			item.Disabled, err = strconv.ParseBool(param.value)
+			item.Plain = !item.Disabled
+		case "refresh":
+			item.Refresh, err = strconv.ParseBool(param.value)
+		case "trim":
+			item.Style.Trim, err = strconv.ParseBool(param.value)
+		case "alternate":
+			alt, err = strconv.ParseBool(param.value)
+		default:
+			if n, ok := paramIndex(param.name); ok {
+				args[n] = param.value
+			} else {
+				item.WithParams(map[string]string{param.name: param.value})
+				item.unknownParams = append(item.unknownParams, param.name)
+			}
+		}
+		if err != nil {
+			return item, false, fmt.Errorf("parameter %q: %w", param.name, err)
+		}
+	}
+	if item.Tooltip == item.Title && item.truncated() {
+		// The tooltip was added by MenuItem.renderSelf for the truncated title.
+		item.Tooltip = ""
+	}
+	if shell != nil {
+		shell.OpenTerminal = terminal
+		// Legacy bash= output uses zero-indexed params.
+		first := 1
+		if _, ok := args[0]; ok {
+			first = 0
+		}
+		for i := first; i < first+len(args); i++ {
+			arg, ok := args[i]
+			if !ok {
+				return item, false, fmt.Errorf("missing parameter \"param%d\"", i)
+			}
+			shell.Args = append(shell.Args, arg)
+		}
+		item.Action = *shell
+	} else if len(args) > 0 {
+		return item, false, errors.New("shell parameters provided without a shell command")
+	}
+	return item, alt, nil
+}
+
+// paramIndex returns n for shell argument parameters named "param<n>".
+func paramIndex(name string) (int, bool) {
+	digits, ok := strings.CutPrefix(name, "param")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil && n >= 0
+}
+
+type param struct {
+	name  string
+	value string
+}
+
+// parseParams splits space separated name=value pairs, where values may be
+// quoted as rendered by quoteParam.
+func parseParams(s string) ([]param, error) {
+	var params []param
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return params, nil
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok || name == "" || strings.Contains(name, " ") {
+			return nil, fmt.Errorf("invalid parameter %q", s)
+		}
+		value, rest, err := parseParamValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, err)
+		}
+		params = append(params, param{name: name, value: value})
+		s = rest
+	}
+}
+
+func parseParamValue(s string) (value, rest string, err error) {
+	if s == "" {
+		return "", "", nil
+	}
+	switch s[0] {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("unterminated quote")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					sb.WriteByte(s[i])
+				}
+			case '"':
+				return sb.String(), s[i+1:], nil
+			default:
+				sb.WriteByte(s[i])
+			}
+		}
+		return "", "", errors.New("unterminated quote")
+	default:
+		value, rest, _ := strings.Cut(s, " ")
+		return value, rest, nil
+	}
+}
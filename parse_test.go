@@ -0,0 +1,255 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestParse_roundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		plugin func() *xbargo.Plugin
+	}{
+		{"runShell", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithText("🐌").WithElements(
+				xbargo.NewMenuItem("🗣️ Say Hello").
+					WithShell("say", "hello", "world").
+					WithAlt(xbargo.NewMenuItem("🗣️ Say Goodbye").WithShell("say", "goodbye", "world")),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("🔋 Battery Preferences").WithShell(
+					"open", "-b", "com.apple.systempreferences",
+					"/System/Library/PreferencePanes/Battery.prefPane",
+				).WithShortcut("b", xbargo.ShiftKey),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("🏠 Home Directory").WithSubMenu(
+					xbargo.NewMenuItem("View Tree").WithAction(
+						xbargo.NewShellAction("tree", "-d", "-L", "1", userHomeDir()).WithTerminal(),
+					),
+					xbargo.NewMenuItem("Copy Path").WithAction(
+						xbargo.NewCopyAction(userHomeDir()),
+					).WithShortcut("c", xbargo.CommandKey),
+				),
+			)
+		}},
+		{"alternateOptions", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithText("Alternate Options").WithElements(
+				xbargo.NewMenuItem("Hello").WithAlt(xbargo.NewMenuItem("Option key is pressed")),
+				xbargo.NewMenuItem("Another"),
+			)
+		}},
+		{"submenus", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithText("Submenu").WithElements(
+				xbargo.NewMenuItem("Places").WithSubMenu(
+					xbargo.NewMenuItem("London"),
+					xbargo.NewMenuItem("Paris"),
+				),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("Fruit").WithSubMenu(
+					xbargo.NewMenuItem("Apple"),
+					xbargo.NewMenuItem("Melon").WithSubMenu(
+						xbargo.NewMenuItem("Watermelon"),
+						xbargo.NewMenuItem("Honeydew"),
+					).WithAlt(xbargo.NewMenuItem("Melons")),
+				),
+				xbargo.NewMenuItem("Last"),
+			)
+		}},
+		{"alternateSubmenus", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithText("Servers").WithElements(
+				xbargo.NewMenuItem("Production").WithSubMenu(
+					xbargo.NewMenuItem("Status"),
+				).WithAlt(xbargo.NewMenuItem("Production (admin)").WithSubMenu(
+					xbargo.NewMenuItem("Restart").WithSubMenu(xbargo.NewMenuItem("Confirm")),
+				)),
+				xbargo.NewMenuItem("Staging"),
+			)
+		}},
+		{"imagesAndLinks", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().
+				WithIcon(bytes.NewReader(beakerImage)).
+				WithElements(
+					xbargo.NewMenuItem("Icon by Daniel Bruce").WithIcon(bytes.NewReader(beakerImage)),
+					xbargo.NewMenuItem("View Source").WithHref("https://iconscout.com/icon/lab-152"),
+				)
+		}},
+		{"styles", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithTitles(
+				xbargo.NewMenuItem("one").WithSFSymbol("cloud.rain"),
+				xbargo.NewMenuItem("two").WithTooltip(`it's "two"`).WithDropdown(false),
+			).WithElements(
+				xbargo.NewMenuItem("Styled").WithStyle(xbargo.Style{
+					MaxLength: 5, Color: "red", Font: "Helvetica Neue", Size: 12, Trim: true,
+				}).WithChecked(true).WithDisabled().WithRefresh(),
+				xbargo.NewMenuItem("Header").WithPlain(),
+				xbargo.NewMenuItem("**bold**").WithStyle(xbargo.Style{Markdown: true}),
+				xbargo.NewMenuItem("\x1b[31mred\x1b[0m :smile:").WithANSI().WithEmojize(false),
+				xbargo.NewMenuItem("Colors").WithColors("#000000", "#ffffff"),
+				xbargo.NewMenuItem("Alert").WithBackgroundColor("red"),
+				xbargo.NewMenuItem("Crème brûlée 🍮").WithStyle(xbargo.Style{MaxLength: 5}),
+				xbargo.NewMenuItem("a|bcdefghijk").WithStyle(xbargo.Style{MaxLength: 5}),
+				xbargo.NewMenuItem("Quotes").WithShell("echo", `it's "quoted" \o/`, "$HOME"),
+				xbargo.NewMenuItem("Backslash").WithShell("echo", `it's C:\dir`),
+				xbargo.NewMenuItem("Line breaks").WithShell("echo", "one\ntwo"),
+				xbargo.NewMenuItem("Command").WithShell("/Applications/My App.app/run\tcafé", "it's"),
+			)
+		}},
+		{"legacyShell", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithText("Legacy").WithLegacyShell().WithElements(
+				xbargo.NewMenuItem("Say").WithShell("say", "hello", "world"),
+			)
+		}},
+		{"metadata", func() *xbargo.Plugin {
+			return xbargo.NewPlugin().WithMetadata(xbargo.Metadata{
+				Title:        "Hello World",
+				Author:       "Jacob LeGrone",
+				Dependencies: []string{"go", "bash"},
+			}).WithVariables(
+				xbargo.Variable{Name: "VAR_GREETING", Default: "hello", Description: "The salutation"},
+				xbargo.Variable{
+					Name:        "VAR_STYLE",
+					Type:        xbargo.VariableTypeSelect,
+					Default:     "normal",
+					Description: "Which style.",
+					Options:     []string{"small", "normal"},
+				},
+			).WithText("👋🌎")
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := renderScript(t, tc.plugin())
+			parsed, err := xbargo.Parse(strings.NewReader(want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := renderScript(t, parsed); got != want {
+				t.Errorf("round trip mismatch\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParse_cleanOutput(t *testing.T) {
+	want := render(t, xbargo.NewPlugin().WithText("Clean").WithCleanOutput().WithElements(
+		xbargo.NewMenuItem("Terminal").WithAction(xbargo.NewShellAction("top").WithTerminal()),
+		xbargo.NewMenuItem("Background").WithShell("say", "hi"),
+		xbargo.NewMenuItem(" padded "),
+		xbargo.NewMenuItem("  trimmed  ").WithStyle(xbargo.Style{Trim: true}),
+	))
+	parsed, err := xbargo.Parse(strings.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(t, parsed.WithCleanOutput()); got != want {
+		t.Errorf("round trip mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// renderScript returns the script header of p followed by its output.
+func renderScript(t *testing.T, p *xbargo.Plugin) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.WriteHeader(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String() + render(t, p)
+}
+
+func TestParse_header(t *testing.T) {
+	p, err := xbargo.Parse(strings.NewReader(
+		"#!/usr/bin/env bash\n" +
+			"# <xbar.title>Hello World</xbar.title>\n" +
+			"Title|\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Metadata == nil || p.Metadata.Title != "Hello World" {
+		t.Errorf("unexpected metadata %+v", p.Metadata)
+	}
+	if p.Title.Title != "Title" || len(p.CycleTitles) != 0 {
+		t.Errorf("unexpected titles %+v %+v", p.Title, p.CycleTitles)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := xbargo.Parse(strings.NewReader(
+		"Title| color=red\n" +
+			"---\n" +
+			"Parent|\n" +
+			"--Child| href=https://example.com\n" +
+			"Parent (alt)| alternate=true\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title.Title != "Title" || p.Title.Style.Color != "red" {
+		t.Errorf("unexpected title %+v", p.Title)
+	}
+	if len(p.Elements) != 1 {
+		t.Fatalf("got %d elements, want 1", len(p.Elements))
+	}
+	parent := p.Elements[0].(*xbargo.MenuItem)
+	if parent.Alt == nil || parent.Alt.Title != "Parent (alt)" {
+		t.Errorf("unexpected alt %+v", parent.Alt)
+	}
+	if len(parent.SubMenu) != 1 || parent.SubMenu[0].Action != xbargo.NewHrefAction("https://example.com") {
+		t.Errorf("unexpected submenu %+v", parent.SubMenu)
+	}
+}
+
+func TestParse_trimDefault(t *testing.T) {
+	p, err := xbargo.Parse(strings.NewReader("  Item  \n---\n  Padded  | trim=false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Title.Style.Trim {
+		t.Error("expected title without trim param to be trimmed")
+	}
+	if p.Elements[0].(*xbargo.MenuItem).Style.Trim {
+		t.Error("expected trim=false to be kept")
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Run("unknown params", func(t *testing.T) {
+		p, err := xbargo.Parse(strings.NewReader("Title| nope=true\n---\nItem| foo=bar color=red\n"))
+		want := "line 1: unsupported parameter \"nope\"\nline 3: unsupported parameter \"foo\""
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+		if p == nil || len(p.Elements) != 1 {
+			t.Fatal("expected plugin to be parsed despite unknown params")
+		}
+		if color := p.Elements[0].(*xbargo.MenuItem).Style.Color; color != "red" {
+			t.Errorf("got color %q, want %q", color, "red")
+		}
+		// Unknown params are preserved when rendered again.
+		want = "Title| refresh=false trim=true nope=true\n---\nItem| color=red refresh=false trim=true foo=bar\n"
+		if got := render(t, p); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "missing plugin title"},
+		{"nesting", "Title|\n---\n----Deep|\n", "line 3: unexpected nesting level 2"},
+		{"orphan alt", "Title|\n---\n--Alt| alternate=true\n", "line 3: unexpected nesting level 1"},
+		{"submenu separator", "Title|\n---\nItem|\n-----\n", "line 4: separators are not supported in submenus"},
+		{"unterminated quote", "Title| tooltip='oops\n", "line 1: parameter \"tooltip\": unterminated quote"},
+		{"missing arg", "Title| shell=echo param2=x\n", "line 1: missing parameter \"param1\""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := xbargo.Parse(strings.NewReader(tc.input))
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
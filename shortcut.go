@@ -0,0 +1,130 @@
+package xbargo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ModifierKey may be used to assign a shortcut to a MenuItem's action.
+type ModifierKey string
+
+const (
+	CommandKey = ModifierKey("CmdOrCtrl")
+	OptionKey  = ModifierKey("OptionOrAlt")
+	ControlKey = ModifierKey("ctrl")
+	ShiftKey   = ModifierKey("shift")
+	SuperKey   = ModifierKey("super")
+)
+
+// modifierTokens maps the lowercase form of each modifier that may precede the
+// key in a MenuItem Shortcut to the casing expected by xbar.
+var modifierTokens = map[string]string{
+	"cmdorctrl":   string(CommandKey),
+	"optionoralt": string(OptionKey),
+	"ctrl":        string(ControlKey),
+	"shift":       string(ShiftKey),
+	"super":       string(SuperKey),
+}
+
+// namedKeyTokens are the named keys that may end a MenuItem Shortcut, in
+// addition to single letters, digits and punctuation.
+var namedKeyTokens = map[string]bool{
+	"backspace": true,
+	"tab":       true,
+	"plus":      true,
+	"return":    true,
+	"enter":     true,
+	"escape":    true,
+	"delete":    true,
+	"home":      true,
+	"end":       true,
+	"left":      true,
+	"right":     true,
+	"up":        true,
+	"down":      true,
+	"space":     true,
+	"f1":        true,
+	"f2":        true,
+	"f3":        true,
+	"f4":        true,
+	"f5":        true,
+	"f6":        true,
+	"f7":        true,
+	"f8":        true,
+	"f9":        true,
+	"f10":       true,
+	"f11":       true,
+	"f12":       true,
+}
+
+// WithShortcut sets a keyboard shortcut for the item, e.g.
+// WithShortcut("k", ShiftKey) for "shift+k".
+//
+// Valid shortcuts are normalized with NormalizeShortcut. Invalid shortcuts are
+// kept as given and reported by Plugin.Validate; use WithShortcutE to handle
+// them immediately instead.
+func (m *MenuItem) WithShortcut(key string, modifiers ...ModifierKey) *MenuItem {
+	shortcut := joinShortcut(key, modifiers)
+	if normalized, err := NormalizeShortcut(shortcut); err == nil {
+		shortcut = normalized
+	}
+	m.Shortcut = shortcut
+	return m
+}
+
+// WithShortcutE is like WithShortcut, but returns an error and leaves the
+// item unchanged if the shortcut is invalid.
+func (m *MenuItem) WithShortcutE(key string, modifiers ...ModifierKey) error {
+	shortcut, err := NormalizeShortcut(joinShortcut(key, modifiers))
+	if err != nil {
+		return err
+	}
+	m.Shortcut = shortcut
+	return nil
+}
+
+func joinShortcut(key string, modifiers []ModifierKey) string {
+	var modStrings []string
+	for _, m := range modifiers {
+		modStrings = append(modStrings, string(m))
+	}
+	return strings.Join(append(modStrings, key), "+")
+}
+
+// NormalizeShortcut validates a MenuItem Shortcut such as "shift+k", returning
+// it with modifiers and named keys in the casing xbar expects, e.g.
+// "cmdorctrl+Return" becomes "CmdOrCtrl+return".
+//
+// The key must come last and be a single letter, digit or punctuation
+// character, or one of the named keys such as "return", "escape", "space",
+// "up" or "f1" through "f12". It may be preceded by any of the ModifierKeys,
+// each used at most once.
+func NormalizeShortcut(shortcut string) (string, error) {
+	tokens := strings.Split(shortcut, "+")
+	seen := map[string]bool{}
+	for i, token := range tokens[:len(tokens)-1] {
+		modifier, ok := modifierTokens[strings.ToLower(token)]
+		if !ok {
+			return "", fmt.Errorf("shortcut %q: unknown modifier %q", shortcut, token)
+		}
+		if seen[modifier] {
+			return "", fmt.Errorf("shortcut %q: duplicate modifier %q", shortcut, token)
+		}
+		seen[modifier] = true
+		tokens[i] = modifier
+	}
+	key := tokens[len(tokens)-1]
+	switch {
+	case key == "":
+		return "", fmt.Errorf("shortcut %q: missing key", shortcut)
+	case len(key) == 1:
+		if key[0] <= ' ' || key[0] >= 0x7f {
+			return "", fmt.Errorf("shortcut %q: unknown key %q", shortcut, key)
+		}
+	case namedKeyTokens[strings.ToLower(key)]:
+		tokens[len(tokens)-1] = strings.ToLower(key)
+	default:
+		return "", fmt.Errorf("shortcut %q: unknown key %q", shortcut, key)
+	}
+	return strings.Join(tokens, "+"), nil
+}
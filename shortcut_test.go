@@ -0,0 +1,63 @@
+package xbargo_test
+
+import (
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestNormalizeShortcut(t *testing.T) {
+	for _, tc := range []struct {
+		shortcut string
+		want     string
+		wantErr  string
+	}{
+		{shortcut: "k", want: "k"},
+		{shortcut: "G", want: "G"},
+		{shortcut: "shift+k", want: "shift+k"},
+		{shortcut: "cmdorctrl+OPTIONORALT+Return", want: "CmdOrCtrl+OptionOrAlt+return"},
+		{shortcut: "Ctrl+Super+F12", want: "ctrl+super+f12"},
+		{shortcut: "shift+plus", want: "shift+plus"},
+		{shortcut: "CmdOrCtrl+,", want: "CmdOrCtrl+,"},
+		{shortcut: "", wantErr: `shortcut "": missing key`},
+		{shortcut: "cmd+", wantErr: `shortcut "cmd+": unknown modifier "cmd"`},
+		{shortcut: "shift+", wantErr: `shortcut "shift+": missing key`},
+		{shortcut: "shift+zzz", wantErr: `shortcut "shift+zzz": unknown key "zzz"`},
+		{shortcut: "shift+f13", wantErr: `shortcut "shift+f13": unknown key "f13"`},
+		{shortcut: "k+shift", wantErr: `shortcut "k+shift": unknown modifier "k"`},
+		{shortcut: "shift+Shift+k", wantErr: `shortcut "shift+Shift+k": duplicate modifier "Shift"`},
+		{shortcut: "shift+é", wantErr: `shortcut "shift+é": unknown key "é"`},
+	} {
+		t.Run(tc.shortcut, func(t *testing.T) {
+			got, err := xbargo.NormalizeShortcut(tc.shortcut)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_WithShortcutE(t *testing.T) {
+	item := xbargo.NewMenuItem("Item")
+	if err := item.WithShortcutE("Escape", xbargo.ModifierKey("SHIFT")); err != nil {
+		t.Fatal(err)
+	}
+	if item.Shortcut != "shift+escape" {
+		t.Errorf("got shortcut %q, want %q", item.Shortcut, "shift+escape")
+	}
+	if err := item.WithShortcutE("zzz", xbargo.ShiftKey); err == nil {
+		t.Error("expected error for invalid shortcut")
+	}
+	if item.Shortcut != "shift+escape" {
+		t.Errorf("invalid shortcut modified item: got %q", item.Shortcut)
+	}
+}
@@ -0,0 +1,58 @@
+package xbargo
+
+import (
+	"bytes"
+	"io"
+)
+
+// StatusLevel is the state of a monitored resource, ordered from least to
+// most severe.
+type StatusLevel int
+
+const (
+	// StatusNone indicates that a resource has no status, e.g. it is unmonitored.
+	StatusNone StatusLevel = iota
+	// StatusAvailable indicates that a resource is healthy.
+	StatusAvailable
+	// StatusPartially indicates that a resource is degraded.
+	StatusPartially
+	// StatusUnavailable indicates that a resource is down.
+	StatusUnavailable
+)
+
+// Icon returns a new reader for the embedded icon representing the level.
+func (l StatusLevel) Icon() io.Reader {
+	switch l {
+	case StatusAvailable:
+		return bytes.NewReader(statusAvailableBytes)
+	case StatusPartially:
+		return bytes.NewReader(statusPartiallyBytes)
+	case StatusUnavailable:
+		return bytes.NewReader(statusUnavailableBytes)
+	default:
+		return bytes.NewReader(statusNoneBytes)
+	}
+}
+
+// RollupStatus returns the icon for the most severe of levels, so that a
+// single icon can summarize the state of many resources. If no levels are
+// given, the icon for StatusNone is returned.
+func RollupStatus(levels ...StatusLevel) io.Reader {
+	worst := StatusNone
+	for _, l := range levels {
+		if l > worst {
+			worst = l
+		}
+	}
+	return worst.Icon()
+}
+
+// WithStatusRollup sets the plugin's title icon to the icon for the most
+// severe of levels. See RollupStatus.
+//
+// Status icons are colored, so the title icon is no longer rendered as a
+// template image.
+func (p *Plugin) WithStatusRollup(levels ...StatusLevel) *Plugin {
+	p.Title.Style.IconImageTemplate = false
+	return p.WithIcon(RollupStatus(levels...))
+}
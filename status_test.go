@@ -0,0 +1,70 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestRollupStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		levels []xbargo.StatusLevel
+		want   string
+	}{
+		{"empty", nil, "Status_None.png"},
+		{"none", []xbargo.StatusLevel{xbargo.StatusNone, xbargo.StatusNone}, "Status_None.png"},
+		{"available", []xbargo.StatusLevel{xbargo.StatusNone, xbargo.StatusAvailable}, "Status_Available.png"},
+		{"partially", []xbargo.StatusLevel{xbargo.StatusAvailable, xbargo.StatusPartially, xbargo.StatusAvailable}, "Status_Partially.png"},
+		{"unavailable", []xbargo.StatusLevel{xbargo.StatusUnavailable, xbargo.StatusPartially, xbargo.StatusAvailable}, "Status_Unavailable.png"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("assets", tc.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(xbargo.RollupStatus(tc.levels...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("expected icon %s", tc.want)
+			}
+
+			p := xbargo.NewPlugin().WithStatusRollup(tc.levels...)
+			wantTitle := "| image=" + base64.StdEncoding.EncodeToString(want) + " refresh=false trim=false\n"
+			if got := render(t, p); got != wantTitle {
+				t.Errorf("got %q, want %q", got, wantTitle)
+			}
+		})
+	}
+}
+
+func TestIconStatus_reuse(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("assets", "Status_Available.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("a").WithIcon(xbargo.IconStatusAvailable),
+		xbargo.NewMenuItem("b").WithIcon(xbargo.IconStatusAvailable),
+		xbargo.NewMenuItem("c").WithIcon(xbargo.StatusAvailable.Icon()),
+		xbargo.NewMenuItem("d").WithIcon(xbargo.StatusAvailable.Icon()),
+	)
+	param := "image=" + base64.StdEncoding.EncodeToString(want)
+	lines := strings.Split(strings.TrimSuffix(render(t, p), "\n"), "\n")[2:]
+	if len(lines) != 4 {
+		t.Fatalf("got %d items, want 4", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, param) {
+			t.Errorf("expected %q to contain icon", line)
+		}
+	}
+}
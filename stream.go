@@ -0,0 +1,55 @@
+package xbargo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamDelimiter tells xbar to discard the previous frame of a streaming
+// plugin and render the lines that follow.
+const streamDelimiter = "~~~"
+
+// Stream implements the streaming Plugin API of xbar by continuously rendering
+// each Plugin received from frames to the standard output.
+//
+// Stream returns nil once frames is closed, or the context's error if it is
+// cancelled first.
+func Stream(ctx context.Context, frames <-chan *Plugin) error {
+	return StreamW(ctx, os.Stdout, frames)
+}
+
+// StreamW renders each Plugin received from frames to the specified writer.
+//
+// Frames after the first are preceded by a ~~~ line so that xbar replaces the
+// previous frame; the first frame needs no delimiter since there is nothing to
+// replace yet. If w has a Flush method, it is called after each frame.
+//
+// This is provided for testing purposes; in other cases the Stream function
+// may be more convenient.
+func StreamW(ctx context.Context, w io.Writer, frames <-chan *Plugin) error {
+	for first := true; ; first = false {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case p, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			if !first {
+				if _, err := fmt.Fprintln(w, streamDelimiter); err != nil {
+					return err
+				}
+			}
+			if err := p.RunW(w); err != nil {
+				return err
+			}
+			if f, ok := w.(interface{ Flush() error }); ok {
+				if err := f.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestStreamW(t *testing.T) {
+	t.Run("frames", func(t *testing.T) {
+		frames := make(chan *xbargo.Plugin, 2)
+		// Variables belong in the script header, so they are not repeated per frame.
+		frames <- xbargo.NewPlugin().WithText("00:01").WithVariables(xbargo.Variable{Name: "VAR_NAME"})
+		frames <- xbargo.NewPlugin().WithText("00:02").WithElements(xbargo.NewMenuItem("Stop"))
+		close(frames)
+
+		var buf bytes.Buffer
+		if err := xbargo.StreamW(context.Background(), &buf, frames); err != nil {
+			t.Fatal(err)
+		}
+		want := "00:01| refresh=false trim=false\n" +
+			"~~~\n" +
+			"00:02| refresh=false trim=false\n" +
+			"---\n" +
+			"Stop| refresh=false trim=false\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := xbargo.StreamW(ctx, &bytes.Buffer{}, make(chan *xbargo.Plugin))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	})
+}
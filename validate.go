@@ -0,0 +1,156 @@
+package xbargo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+)
+
+const (
+	// DefaultMaxSize is the largest rendered output, in bytes, permitted by
+	// Validate when Plugin.MaxSize is unset. xbar becomes sluggish when
+	// plugins produce much more output than this, usually due to large icons.
+	DefaultMaxSize = 128 << 10
+	// MaxIconDimension is the largest width or height, in pixels, of icons
+	// permitted by Validate.
+	MaxIconDimension = 256
+)
+
+// Validate reports problems with the plugin configuration that xbar would
+// otherwise silently ignore or render incorrectly:
+//
+//   - titles must have text or an icon, and Title must not be hidden
+//   - shortcuts must only use known modifiers and keys
+//   - custom parameter names must not be empty or contain whitespace, "=" or "|"
+//   - icons must be at most MaxIconDimension pixels wide and high
+//   - rendered output must not exceed MaxSize bytes
+//
+// Icons are only checked if they implement io.Seeker, since otherwise they
+// could not be rendered after being read; the same applies to the size of
+// the plugin as a whole.
+//
+// All problems found are combined into a single error.
+func (p *Plugin) Validate() error {
+	var (
+		errs       []error
+		measurable = true
+	)
+	if p.Title != nil && p.Title.Hidden {
+		errs = append(errs, errors.New("plugin title must not be hidden"))
+	}
+	for _, title := range p.titles() {
+		if title == nil {
+			errs = append(errs, errors.New("plugin title must not be nil"))
+			measurable = false
+			continue
+		}
+		if title.Title == "" && title.Icon == nil && title.SFSymbol == "" {
+			errs = append(errs, errors.New("plugin title must have text or an icon"))
+		}
+		errs = append(errs, title.validate(&measurable)...)
+	}
+	for _, el := range p.Elements {
+		errs = append(errs, validateElement(el, &measurable)...)
+	}
+	if limit := p.maxSize(); limit > 0 && measurable {
+		if size, err := p.RenderedSize(); err == nil && size > limit {
+			errs = append(errs, fmt.Errorf("rendered output is %d bytes, exceeding the limit of %d bytes", size, limit))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Plugin) maxSize() int {
+	if p.MaxSize == 0 {
+		return DefaultMaxSize
+	}
+	return p.MaxSize
+}
+
+// RenderedSize returns the number of bytes that RunW would write.
+//
+// Icons are read in order to measure them. Those that implement io.Seeker
+// have their offset restored, but any others are consumed.
+func (p *Plugin) RenderedSize() (int, error) {
+	var w countingWriter
+	if err := p.RenderW(&w, p.renderOptions()); err != nil {
+		return 0, err
+	}
+	return int(w), nil
+}
+
+type countingWriter int
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	*w += countingWriter(len(b))
+	return len(b), nil
+}
+
+func validateElement(el XbarElement, measurable *bool) []error {
+	item, ok := el.(*MenuItem)
+	if !ok {
+		return nil
+	}
+	return item.validate(measurable)
+}
+
+// validate reports problems with the menu item and its alternate and
+// submenus, clearing measurable if any of their icons can only be read once.
+// Hidden items are not rendered, so they are not validated.
+func (m *MenuItem) validate(measurable *bool) []error {
+	if m.Hidden {
+		return nil
+	}
+	var errs []error
+	if m.Shortcut != "" {
+		if _, err := NormalizeShortcut(m.Shortcut); err != nil {
+			errs = append(errs, fmt.Errorf("menu item %q: %w", m.Title, err))
+		}
+	}
+	names := make([]string, 0, len(m.Params))
+	for name := range m.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validParamName(name) {
+			errs = append(errs, fmt.Errorf("menu item %q: invalid parameter name %q", m.Title, name))
+		}
+	}
+	if m.Icon != nil && m.SFSymbol == "" {
+		if _, ok := m.Icon.(io.Seeker); ok {
+			if err := validateIcon(m.Icon); err != nil {
+				errs = append(errs, fmt.Errorf("menu item %q: %w", m.Title, err))
+			}
+		} else {
+			*measurable = false
+		}
+	}
+	if m.Alt != nil {
+		errs = append(errs, m.Alt.validate(measurable)...)
+	}
+	for _, child := range m.SubMenu {
+		errs = append(errs, child.validate(measurable)...)
+	}
+	return errs
+}
+
+// validateIcon checks the dimensions of icons in formats that can be decoded.
+// Read errors are left to be reported when the icon is rendered.
+func validateIcon(r io.Reader) error {
+	b, err := readIcon(r)
+	if err != nil {
+		return nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	if cfg.Width > MaxIconDimension || cfg.Height > MaxIconDimension {
+		return fmt.Errorf("icon is %dx%d pixels, larger than %dx%d", cfg.Width, cfg.Height, MaxIconDimension, MaxIconDimension)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package xbargo_test
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+func TestPlugin_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		plugin  *xbargo.Plugin
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithShortcut("k", xbargo.ShiftKey, xbargo.CommandKey),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("Parent").WithSubMenu(
+					xbargo.NewMenuItem("Child").WithShortcut("f12"),
+				).WithAlt(xbargo.NewMenuItem("Alt")),
+			),
+		},
+		{
+			name:   "icon title",
+			plugin: xbargo.NewPlugin().WithIcon(bytes.NewReader(beakerImage)),
+		},
+		{
+			name:    "empty title",
+			plugin:  xbargo.NewPlugin(),
+			wantErr: []string{"plugin title must have text or an icon"},
+		},
+		{
+			name: "empty cycle title",
+			plugin: xbargo.NewPlugin().WithTitles(
+				xbargo.NewMenuItem("first"),
+				xbargo.NewMenuItem(""),
+			),
+			wantErr: []string{"plugin title must have text or an icon"},
+		},
+		{
+			name: "unknown key",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithShortcut("zzz", xbargo.ShiftKey),
+			),
+			wantErr: []string{`menu item "Item": shortcut "shift+zzz": unknown key "zzz"`},
+		},
+		{
+			name: "missing key",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithShortcut("", xbargo.CommandKey),
+			),
+			wantErr: []string{`menu item "Item": shortcut "CmdOrCtrl+": missing key`},
+		},
+		{
+			name: "unknown modifier",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithShortcut("k", xbargo.ModifierKey("hyper")),
+			),
+			wantErr: []string{`menu item "Item": shortcut "hyper+k": unknown modifier "hyper"`},
+		},
+		{
+			name:    "hidden title",
+			plugin:  xbargo.NewPlugin().WithTitles(xbargo.NewMenuItem("Title").When(false)),
+			wantErr: []string{"plugin title must not be hidden"},
+		},
+		{
+			name: "invalid param names",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithParams(map[string]string{"ok": "1", "": "2", "a b": "3"}),
+			),
+			wantErr: []string{`menu item "Item": invalid parameter name ""`, `menu item "Item": invalid parameter name "a b"`},
+		},
+		{
+			name: "multiple",
+			plugin: xbargo.NewPlugin().WithElements(
+				xbargo.NewMenuItem("Parent").WithSubMenu(
+					xbargo.NewMenuItem("Child").WithShortcut("nope"),
+				),
+			),
+			wantErr: []string{
+				"plugin title must have text or an icon",
+				`menu item "Child": shortcut "nope": unknown key "nope"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.plugin.Validate()
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q", tc.wantErr)
+			}
+			want := strings.Join(tc.wantErr, "\n")
+			if got := err.Error(); got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPlugin_RenderedSize(t *testing.T) {
+	p := xbargo.NewPlugin().WithIcon(bytes.NewReader(beakerImage)).WithElements(
+		xbargo.NewMenuItem("Item"),
+	)
+	size, err := p.RenderedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Measuring must not consume seekable icons.
+	if got := render(t, p); len(got) != size {
+		t.Errorf("got size %d, want %d", size, len(got))
+	}
+	for _, tc := range []struct {
+		name    string
+		maxSize int
+		wantErr bool
+	}{
+		{"default", 0, false},
+		{"at limit", size, false},
+		{"over limit", size - 1, true},
+		{"disabled", -1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.WithMaxSize(tc.maxSize).Validate()
+			if tc.wantErr {
+				want := fmt.Sprintf("rendered output is %d bytes, exceeding the limit of %d bytes", size, size-1)
+				if err == nil || err.Error() != want {
+					t.Errorf("got error %v, want %q", err, want)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPlugin_Validate_iconDimensions(t *testing.T) {
+	for _, tc := range []struct {
+		width, height int
+		wantErr       string
+	}{
+		{xbargo.MaxIconDimension, xbargo.MaxIconDimension, ""},
+		{xbargo.MaxIconDimension + 1, 1, `menu item "Item": icon is 257x1 pixels, larger than 256x256`},
+		{1, xbargo.MaxIconDimension + 1, `menu item "Item": icon is 1x257 pixels, larger than 256x256`},
+	} {
+		t.Run(fmt.Sprintf("%dx%d", tc.width, tc.height), func(t *testing.T) {
+			icon := bytes.NewReader(encodeTestImage(t, tc.width, tc.height, png.Encode))
+			p := xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithIcon(icon),
+			)
+			err := p.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+			if icon.Len() != int(icon.Size()) {
+				t.Error("expected icon to be unread after validation")
+			}
+		})
+	}
+}
+
+func TestPlugin_RunW_strict(t *testing.T) {
+	var buf bytes.Buffer
+	if err := xbargo.NewPlugin().WithStrict().RunW(&buf); err == nil {
+		t.Error("expected validation error")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+	if err := xbargo.NewPlugin().RunW(&buf); err != nil {
+		t.Errorf("unexpected error without strict validation: %v", err)
+	}
+}
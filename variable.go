@@ -0,0 +1,63 @@
+package xbargo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A VariableType determines how a Variable may be edited in the xbar app.
+type VariableType string
+
+const (
+	VariableTypeString  = VariableType("string")
+	VariableTypeNumber  = VariableType("number")
+	VariableTypeBoolean = VariableType("boolean")
+	VariableTypeSelect  = VariableType("select")
+)
+
+// A Variable is a setting that users can change from the xbar app.
+//
+// xbar passes the configured value to the plugin as an environment variable.
+//
+// More information:
+// https://github.com/matryer/xbar-plugins/blob/main/CONTRIBUTING.md#variables
+type Variable struct {
+	// The name of the environment variable, e.g. "VAR_NAME".
+	Name string `json:",omitempty"`
+	// The type of the variable. Defaults to VariableTypeString.
+	Type VariableType `json:",omitempty"`
+	// The value to use when the variable has not been configured.
+	Default string `json:",omitempty"`
+	// A short description of the variable.
+	Description string `json:",omitempty"`
+	// Options lists the allowed values for VariableTypeSelect.
+	Options []string `json:",omitempty"`
+}
+
+// Value returns the value of the variable from the environment, or Default
+// if it is unset or empty.
+func (v Variable) Value() string {
+	if value := os.Getenv(v.Name); value != "" {
+		return value
+	}
+	return v.Default
+}
+
+func (v Variable) render(w io.Writer) error {
+	typ := v.Type
+	if typ == "" {
+		typ = VariableTypeString
+	}
+	def := v.Default
+	switch typ {
+	case VariableTypeString, VariableTypeSelect:
+		def = fmt.Sprintf("%q", def)
+	}
+	declaration := fmt.Sprintf("%s(%s=%s): %s", typ, v.Name, def, v.Description)
+	if typ == VariableTypeSelect {
+		declaration = fmt.Sprintf("%s [%s]", declaration, strings.Join(v.Options, ", "))
+	}
+	return printMetadataTag(w, "var", declaration)
+}
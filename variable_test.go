@@ -0,0 +1,68 @@
+package xbargo_test
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/jlegrone/xbargo"
+)
+
+// Example of how to declare variables that users can configure in the xbar app.
+func ExamplePlugin_variables() {
+	greeting := xbargo.Variable{
+		Name:        "VAR_GREETING",
+		Default:     "hello",
+		Description: "The salutation to use",
+	}
+	p := xbargo.NewPlugin().WithVariables(
+		greeting,
+		xbargo.Variable{
+			Name:        "VAR_COUNT",
+			Type:        xbargo.VariableTypeNumber,
+			Default:     "3",
+			Description: "How many times to greet",
+		},
+		xbargo.Variable{
+			Name:        "VAR_LOUD",
+			Type:        xbargo.VariableTypeBoolean,
+			Default:     "false",
+			Description: "Whether to shout",
+		},
+		xbargo.Variable{
+			Name:        "VAR_STYLE",
+			Type:        xbargo.VariableTypeSelect,
+			Default:     "normal",
+			Description: "Which style to use.",
+			Options:     []string{"small", "normal", "big"},
+		},
+	).WithText(greeting.Value())
+	// Like metadata, variables are declared in the script that runs the plugin.
+	if err := p.WriteHeader(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// # <xbar.var>string(VAR_GREETING="hello"): The salutation to use</xbar.var>
+	// # <xbar.var>number(VAR_COUNT=3): How many times to greet</xbar.var>
+	// # <xbar.var>boolean(VAR_LOUD=false): Whether to shout</xbar.var>
+	// # <xbar.var>select(VAR_STYLE="normal"): Which style to use. [small, normal, big]</xbar.var>
+}
+
+func TestVariable_Value(t *testing.T) {
+	v := xbargo.Variable{Name: "VAR_XBARGO_TEST", Default: "default"}
+	for _, tc := range []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"empty", "", "default"},
+		{"set", "custom", "custom"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(v.Name, tc.env)
+			if got := v.Value(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
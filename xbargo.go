@@ -4,13 +4,19 @@ package xbargo
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"image/color"
 	"io"
 	"log"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 var (
@@ -30,6 +36,10 @@ var (
 	//go:embed assets/Status_Unavailable.png
 	statusUnavailableBytes []byte
 	// IconStatusAvailable is a small green indicator, similar to iChat’s available image.
+	//
+	// Icons are rewound after they are rendered, so the status icons may be
+	// used by any number of menu items. StatusLevel.Icon returns a new reader
+	// for each call instead.
 	IconStatusAvailable = bytes.NewReader(statusAvailableBytes)
 	// IconStatusNone is a small clear indicator.
 	IconStatusNone = bytes.NewReader(statusNoneBytes)
@@ -41,8 +51,8 @@ var (
 
 // An XbarElement may be either a MenuItem or Separator.
 type XbarElement interface {
-	renderSelf() string
-	renderAlt() string
+	renderSelf(opts RenderOptions, isAlt bool) (string, error)
+	alternate() XbarElement
 	children() []XbarElement
 }
 
@@ -50,12 +60,12 @@ type XbarElement interface {
 // menu items.
 type Separator struct{}
 
-func (Separator) renderSelf() string {
-	return "---"
+func (Separator) renderSelf(RenderOptions, bool) (string, error) {
+	return "---", nil
 }
 
-func (Separator) renderAlt() string {
-	return ""
+func (Separator) alternate() XbarElement {
+	return nil
 }
 
 func (Separator) children() []XbarElement {
@@ -66,17 +76,45 @@ type Style struct {
 	// Setting MaxLength will truncate text to the specified number of characters.
 	//
 	// A … will be added to any truncated strings, as well as a tooltip displaying
-	// the full string.
-	MaxLength uint
+	// the full string unless the item has its own Tooltip. Characters are
+	// counted as runes, so each emoji or accented letter counts once.
+	MaxLength uint `json:",omitempty"`
 	// Change the Title color, e.g. "red" or "#ff0000"
-	Color string
+	Color string `json:",omitempty"`
+	// ColorDark overrides Color when the menu bar is in dark mode.
+	//
+	// ColorDark is ignored unless Color is also set.
+	ColorDark string `json:",omitempty"`
+	// Change the background color of the line, e.g. "red" or "#ff0000", to
+	// highlight it. Versions of xbar that do not support bgcolor ignore it.
+	BackgroundColor string `json:",omitempty"`
+	// Change the Title font, e.g. "Menlo" or "Helvetica Neue".
+	Font string `json:",omitempty"`
+	// Change the Title font size in points.
+	Size uint `json:",omitempty"`
 	// An Icon used in plugin titles should have IconImageTemplate enabled.
 	//
 	// A template image discards color information and uses a mask to produce the
 	// appearance you see onscreen. Template images automatically adapt to the user’s
 	// appearance settings, so they look good on both dark and light menu bars, and
 	// when your menu bar extra is selected.
-	IconImageTemplate bool
+	IconImageTemplate bool `json:",omitempty"`
+	// Trim leading and trailing whitespace from the Title.
+	//
+	// Defaults to false, which preserves any padding in the Title.
+	Trim bool `json:",omitempty"`
+	// ANSI enables interpreting ANSI color escape codes in the Title, e.g. from
+	// colorized command output.
+	ANSI bool `json:",omitempty"`
+	// Emojize controls whether xbar converts text like :smile: into emoji.
+	//
+	// Defaults to xbar's behavior (enabled) when nil.
+	Emojize *bool `json:",omitempty"`
+	// Markdown renders the Title as markdown, e.g. "**bold**".
+	//
+	// Markdown syntax is passed through unescaped; only pipes, line breaks and
+	// a leading hyphen in the Title are replaced as usual.
+	Markdown bool `json:",omitempty"`
 }
 
 // An Action may be either an HrefAction or ShellAction.
@@ -109,14 +147,68 @@ func NewShellAction(command string, args ...string) ShellAction {
 }
 
 // NewCopyAction copies the given text to the user's clipboard.
+//
+// The text is base64 encoded before being passed to the shell, so any bytes
+// including newlines, quotes and shell metacharacters are copied exactly.
 func NewCopyAction(text string) ShellAction {
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
 	return ShellAction{
 		Command:      "/bin/bash",
-		Args:         []string{"-c", fmt.Sprintf("echo -n %s | pbcopy", text)},
+		Args:         []string{"-c", fmt.Sprintf("echo %s | base64 --decode | pbcopy", encoded)},
 		OpenTerminal: false,
 	}
 }
 
+// NewAppleScriptAction runs the given AppleScript with osascript.
+//
+// Each line of a multi-line script is passed as a separate -e argument.
+func NewAppleScriptAction(script string) ShellAction {
+	var args []string
+	for _, line := range strings.Split(script, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			args = append(args, "-e", line)
+		}
+	}
+	return NewShellAction("osascript", args...)
+}
+
+// NewNotificationAction displays a macOS notification. The title and subtitle
+// are optional.
+func NewNotificationAction(title, subtitle, body string) ShellAction {
+	script := fmt.Sprintf("display notification %s", appleScriptQuote(body))
+	if title != "" {
+		script = fmt.Sprintf("%s with title %s", script, appleScriptQuote(title))
+	}
+	if subtitle != "" {
+		script = fmt.Sprintf("%s subtitle %s", script, appleScriptQuote(subtitle))
+	}
+	return NewAppleScriptAction(script)
+}
+
+// appleScriptQuote quotes s as an AppleScript string literal. Line breaks are
+// escaped so that the literal stays on one line of the script.
+func appleScriptQuote(s string) string {
+	return `"` + appleScriptReplacer.Replace(s) + `"`
+}
+
+var appleScriptReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// NewOpenFileAction opens a file or folder with its default application.
+func NewOpenFileAction(path string) ShellAction {
+	return NewShellAction("open", path)
+}
+
+// NewRevealAction selects a file or folder in a new Finder window.
+func NewRevealAction(path string) ShellAction {
+	return NewShellAction("open", "-R", path)
+}
+
+// NewOpenWithAppAction opens a file or folder with the application identified
+// by bundleID, e.g. "com.apple.TextEdit".
+func NewOpenWithAppAction(path, bundleID string) ShellAction {
+	return NewShellAction("open", "-b", bundleID, path)
+}
+
 // HrefAction opens a URI on click.
 type HrefAction struct {
 	URI string
@@ -131,11 +223,72 @@ func NewHrefAction(uri string) HrefAction {
 	}
 }
 
+// NewHrefActionURL opens base with the given query parameters appended,
+// percent-encoding them as needed. The rest of base, including any query
+// parameters already present and a trailing fragment, is preserved as written.
+func NewHrefActionURL(base string, query url.Values) HrefAction {
+	encoded := query.Encode()
+	if encoded == "" {
+		return NewHrefAction(base)
+	}
+	uri, fragment, hasFragment := strings.Cut(base, "#")
+	switch {
+	case strings.HasSuffix(uri, "?"), strings.HasSuffix(uri, "&"):
+	case strings.Contains(uri, "?"):
+		uri += "&"
+	default:
+		uri += "?"
+	}
+	uri += encoded
+	if hasFragment {
+		uri += "#" + fragment
+	}
+	return NewHrefAction(uri)
+}
+
+// NewMailtoAction composes an email in the user's mail client. The subject and
+// body are optional.
+func NewMailtoAction(to, subject, body string) HrefAction {
+	var params []string
+	if subject != "" {
+		params = append(params, "subject="+mailtoEscape(subject))
+	}
+	if body != "" {
+		params = append(params, "body="+mailtoEscape(body))
+	}
+	uri := "mailto:" + url.PathEscape(to)
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return NewHrefAction(uri)
+}
+
+// mailtoEscape escapes s for use in a mailto query. Mail clients do not decode
+// + as a space, so spaces are percent-encoded instead.
+func mailtoEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// NewRefreshAction refreshes the plugin with the given file name, e.g.
+// "helloworld.1m.sh", without running a shell command.
+func NewRefreshAction(pluginName string) HrefAction {
+	return NewHrefAction("xbar://app.xbarapp.com/refreshPlugin?path=" + url.QueryEscape(pluginName))
+}
+
+// NewRefreshAllAction refreshes all installed plugins.
+func NewRefreshAllAction() HrefAction {
+	return NewHrefAction("xbar://app.xbarapp.com/refreshAllPlugins")
+}
+
 // A MenuItem may be configured to initiate an action, toggle a state on or off,
 // or display a submenu of additional menu items when selected or in response to
 // an associated keyboard shortcut.
 type MenuItem struct {
 	// The text of the menu item. May be empty if Icon is set.
+	//
+	// Pipe characters are rendered as a fullwidth vertical line (｜), line
+	// breaks are replaced with spaces and a leading hyphen is rendered as a
+	// Unicode hyphen (‐), since all of these have special meaning to xbar.
 	Title string
 	// An Icon can be used to help people recognize menu items and associate them with
 	// content.
@@ -149,8 +302,19 @@ type MenuItem struct {
 	// More information:
 	// https://developer.apple.com/design/human-interface-guidelines/macos/menus/menu-anatomy/#using-icons-in-menus
 	Icon io.Reader
+	// SFSymbol is the name of an SF Symbol to display, e.g. "cloud.rain".
+	//
+	// SF Symbols adapt to the user's appearance settings and are much lighter
+	// than embedding image data. If both SFSymbol and Icon are set, SFSymbol takes
+	// priority and Icon is ignored.
+	//
+	// Browse available symbols with Apple's SF Symbols app:
+	// https://developer.apple.com/sf-symbols/
+	SFSymbol string
 	// Style configures the Title and/or Icon style.
 	Style Style
+	// Tooltip is displayed when hovering over the item.
+	Tooltip string
 	// Shortcut sets a keyboard shortcut for the item.
 	//
 	// Use + to create combinations, e.g. "shift+k". Example options:
@@ -171,8 +335,25 @@ type MenuItem struct {
 	// Action determines what the MenuItem will do on click or when the Shortcut
 	// keys are pressed.
 	//
-	// MenuItems that have no action configured will appear disabled.
+	// MenuItems that have no action configured will appear disabled, unless Plain
+	// is set.
 	Action Action
+	// Disabled greys out the item even if it has an Action configured.
+	Disabled bool
+	// Plain renders an item without an Action as normal text rather than
+	// appearing disabled, e.g. for section headers. Clicking it does nothing.
+	//
+	// Disabled takes priority over Plain.
+	Plain bool
+	// Dropdown controls whether a title item is also shown in the dropdown menu.
+	// Setting it to false shows the item only in the menu bar, which is useful
+	// with Plugin.CycleTitles.
+	//
+	// Defaults to xbar's behavior (shown) when nil.
+	Dropdown *bool
+	// Display a checkmark next to the item, e.g. to indicate that a setting is
+	// toggled on.
+	Checked bool
 	// Make the item refresh the plugin it belongs to.
 	// If the item runs a script, refresh is performed after the script finishes.
 	Refresh bool
@@ -181,6 +362,16 @@ type MenuItem struct {
 	Alt *MenuItem
 	// Items to nest in a submenu under the current item.
 	SubMenu []*MenuItem
+	// Params are additional parameters to render, for those that xbargo does
+	// not support yet or that are specific to a fork of xbar. See WithParams.
+	Params map[string]string
+	// Omit the item, along with its alternate and submenu, from the output
+	// entirely. Unlike Disabled, nothing is shown in the dropdown.
+	//
+	// A plugin's Title cannot be hidden, but its CycleTitles can. Separators
+	// that hiding an item would leave leading, trailing or doubled are omitted
+	// as well.
+	Hidden bool
 }
 
 func NewMenuItem(title string) *MenuItem {
@@ -192,11 +383,76 @@ func (m *MenuItem) WithStyle(style Style) *MenuItem {
 	return m
 }
 
+// WithColorValue sets the Title color from a color.Color, e.g. to generate
+// colors programmatically. xbar does not support transparency, so alpha is
+// discarded.
+func (m *MenuItem) WithColorValue(c color.Color) *MenuItem {
+	m.Style.Color = hexColor(c)
+	return m
+}
+
+// hexColor formats c as "#rrggbb", discarding alpha.
+func hexColor(c color.Color) string {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B)
+}
+
+// WithBackgroundColor highlights the item with a background color, e.g. "red"
+// or "#ff0000".
+func (m *MenuItem) WithBackgroundColor(color string) *MenuItem {
+	m.Style.BackgroundColor = color
+	return m
+}
+
+// WithBackgroundColorValue sets the background color from a color.Color,
+// discarding alpha as with WithColorValue.
+func (m *MenuItem) WithBackgroundColorValue(c color.Color) *MenuItem {
+	m.Style.BackgroundColor = hexColor(c)
+	return m
+}
+
+// WithColors sets separate Title colors for light and dark menu bars.
+func (m *MenuItem) WithColors(light, dark string) *MenuItem {
+	m.Style.Color = light
+	m.Style.ColorDark = dark
+	return m
+}
+
+func (m *MenuItem) WithANSI() *MenuItem {
+	m.Style.ANSI = true
+	return m
+}
+
+func (m *MenuItem) WithEmojize(emojize bool) *MenuItem {
+	m.Style.Emojize = &emojize
+	return m
+}
+
 func (m *MenuItem) WithRefresh() *MenuItem {
 	m.Refresh = true
 	return m
 }
 
+func (m *MenuItem) WithDropdown(dropdown bool) *MenuItem {
+	m.Dropdown = &dropdown
+	return m
+}
+
+func (m *MenuItem) WithChecked(checked bool) *MenuItem {
+	m.Checked = checked
+	return m
+}
+
+func (m *MenuItem) WithDisabled() *MenuItem {
+	m.Disabled = true
+	return m
+}
+
+func (m *MenuItem) WithPlain() *MenuItem {
+	m.Plain = true
+	return m
+}
+
 func (m *MenuItem) WithAction(action Action) *MenuItem {
 	m.Action = action
 	return m
@@ -217,38 +473,107 @@ func (m *MenuItem) WithIcon(icon io.Reader) *MenuItem {
 	return m
 }
 
+func (m *MenuItem) WithSFSymbol(name string) *MenuItem {
+	m.SFSymbol = name
+	return m
+}
+
 func (m *MenuItem) WithAlt(item *MenuItem) *MenuItem {
 	m.Alt = item
 	return m
 }
 
-// A ModifierKey may be used to assign a shortcut to a MenuItem's action.
-type ModifierKey string
+func (m *MenuItem) WithTooltip(tooltip string) *MenuItem {
+	m.Tooltip = tooltip
+	return m
+}
 
-const (
-	CommandKey = ModifierKey("CmdOrCtrl")
-	OptionKey  = ModifierKey("OptionOrAlt")
-	ControlKey = ModifierKey("ctrl")
-	ShiftKey   = ModifierKey("shift")
-)
+func (m *MenuItem) WithSubMenu(items ...*MenuItem) *MenuItem {
+	m.SubMenu = append(m.SubMenu, items...)
+	return m
+}
 
-func (m *MenuItem) WithShortcut(key string, modifiers ...ModifierKey) *MenuItem {
-	var modStrings []string
-	for _, m := range modifiers {
-		modStrings = append(modStrings, string(m))
+// WithParams adds arbitrary parameters to the item, quoting their values as
+// needed.
+//
+// A parameter that xbargo would otherwise render, such as color, is replaced
+// by the given value in its usual position. Other parameters follow the
+// standard ones, sorted by name. The alternate parameter is reserved for Alt
+// and is ignored.
+//
+// Names must not be empty or contain whitespace, "=" or "|", since they could
+// not be parsed; rendering an item with such a name returns an error.
+func (m *MenuItem) WithParams(params map[string]string) *MenuItem {
+	if m.Params == nil {
+		m.Params = make(map[string]string, len(params))
+	}
+	for name, value := range params {
+		m.Params[name] = value
 	}
-	m.Shortcut = strings.Join(append(modStrings, key), "+")
 	return m
 }
 
-func (m *MenuItem) WithSubMenu(items ...*MenuItem) *MenuItem {
-	m.SubMenu = append(m.SubMenu, items...)
+// When hides the item unless cond is true, so that alternative layouts can be
+// built without branching, e.g.
+//
+//	xbargo.NewMenuItem("All systems operational").When(healthy),
+//	xbargo.NewMenuItem("Checks failing").WithSubMenu(failures...).When(!healthy),
+func (m *MenuItem) When(cond bool) *MenuItem {
+	m.Hidden = !cond
 	return m
 }
 
-func (m *MenuItem) renderSelf() string {
+// tooltip returns the item's Tooltip, defaulting to the full Title if it will
+// be truncated by MaxLength. The default is escaped in the same way as the
+// rendered title.
+func (m *MenuItem) tooltip() string {
+	if m.Tooltip == "" && m.truncated() {
+		return titleReplacer.Replace(m.Title)
+	}
+	return m.Tooltip
+}
+
+// truncated reports whether xbar will truncate the rendered Title to
+// MaxLength characters.
+func (m *MenuItem) truncated() bool {
+	return m.Style.MaxLength > 0 && uint(utf8.RuneCountInString(titleReplacer.Replace(m.Title))) > m.Style.MaxLength
+}
+
+// titleReplacer escapes characters that would otherwise break the xbar line
+// format. Pipes separate the title from its parameters, so they are replaced
+// with a visually equivalent fullwidth vertical line, and line breaks would
+// start a new menu item, so they are collapsed into spaces.
+var titleReplacer = strings.NewReplacer(
+	"|", "｜",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// escapeTitle escapes title for use as the title of a menu item line. On top of
+// titleReplacer, a leading hyphen is replaced with a visually equivalent
+// Unicode hyphen, since xbar reads leading dashes as nesting or a separator.
+func escapeTitle(title string) string {
+	title = titleReplacer.Replace(title)
+	if rest, ok := strings.CutPrefix(title, "-"); ok {
+		return "\u2010" + rest
+	}
+	return title
+}
+
+// lineBreakReplacer collapses line breaks in parameter values, which would
+// otherwise start a new menu item.
+var lineBreakReplacer = strings.NewReplacer(
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// renderSelf renders the item's line, without any nesting prefix. Parameters
+// must be appended in the order documented on Plugin.RenderW.
+func (m *MenuItem) renderSelf(opts RenderOptions, isAlt bool) (string, error) {
 	parts := []string{
-		fmt.Sprintf("%s|", m.Title),
+		fmt.Sprintf("%s|", escapeTitle(m.Title)),
 	}
 	if m.Shortcut != "" {
 		parts = append(parts, fmt.Sprintf("key=%s", m.Shortcut))
@@ -257,24 +582,59 @@ func (m *MenuItem) renderSelf() string {
 		parts = append(parts, fmt.Sprintf("length=%d", m.Style.MaxLength))
 	}
 	if m.Style.Color != "" {
-		parts = append(parts, fmt.Sprintf("color=%s", m.Style.Color))
+		color := m.Style.Color
+		if m.Style.ColorDark != "" {
+			color = fmt.Sprintf("%s,%s", color, m.Style.ColorDark)
+		}
+		parts = append(parts, fmt.Sprintf("color=%s", color))
+	}
+	if m.Style.BackgroundColor != "" {
+		parts = append(parts, fmt.Sprintf("bgcolor=%s", m.Style.BackgroundColor))
+	}
+	if m.Style.Font != "" {
+		parts = append(parts, fmt.Sprintf("font=%s", quoteParamIfNeeded(m.Style.Font)))
+	}
+	if m.Style.Size > 0 {
+		parts = append(parts, fmt.Sprintf("size=%d", m.Style.Size))
+	}
+	if m.Style.ANSI {
+		parts = append(parts, "ansi=true")
+	}
+	if m.Style.Emojize != nil && !(opts.OmitDefaults && *m.Style.Emojize) {
+		parts = append(parts, fmt.Sprintf("emojize=%t", *m.Style.Emojize))
+	}
+	if m.Style.Markdown {
+		parts = append(parts, "md=true")
+	}
+	if tooltip := m.tooltip(); tooltip != "" {
+		parts = append(parts, fmt.Sprintf("tooltip=%s", quoteParamIfNeeded(lineBreakReplacer.Replace(tooltip))))
 	}
 	if m.Action != nil {
 		switch action := m.Action.(type) {
 		case HrefAction:
-			parts = append(parts, fmt.Sprintf("href=%s", action.URI))
+			parts = append(parts, fmt.Sprintf("href=%s", quoteParamIfNeeded(action.URI)))
 		case ShellAction:
-			part := fmt.Sprintf("terminal=%t shell=%q", action.OpenTerminal, action.Command)
+			// Legacy plugins use bash= with zero-indexed params, while xbar uses
+			// shell= with params starting from param1.
+			command, firstParam := "shell", 1
+			if opts.LegacyShell {
+				command, firstParam = "bash", 0
+			}
+			if !(opts.OmitDefaults && action.OpenTerminal) {
+				parts = append(parts, fmt.Sprintf("terminal=%t", action.OpenTerminal))
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s", command, quoteParam(action.Command)))
 			for i, arg := range action.Args {
-				part = fmt.Sprintf("%s param%d='%s'", part, i+1, arg)
+				parts = append(parts, fmt.Sprintf("param%d=%s", i+firstParam, quoteParam(arg)))
 			}
-			parts = append(parts, part)
 		}
 	}
-	if m.Icon != nil {
-		b, err := io.ReadAll(m.Icon)
+	if m.SFSymbol != "" {
+		parts = append(parts, fmt.Sprintf("sfimage=%s", m.SFSymbol))
+	} else if m.Icon != nil {
+		b, err := readIcon(m.Icon)
 		if err != nil {
-			panic(err)
+			return "", fmt.Errorf("reading icon for menu item %q: %w", m.Title, err)
 		}
 		imageType := "image"
 		if m.Style.IconImageTemplate {
@@ -282,19 +642,107 @@ func (m *MenuItem) renderSelf() string {
 		}
 		parts = append(parts, fmt.Sprintf("%s=%s", imageType, base64.StdEncoding.EncodeToString(b)))
 	}
-	parts = append(parts,
-		fmt.Sprintf("refresh=%t", m.Refresh),
-		"trim=false",
-	)
+	if m.Dropdown != nil && !(opts.OmitDefaults && *m.Dropdown) {
+		parts = append(parts, fmt.Sprintf("dropdown=%t", *m.Dropdown))
+	}
+	if m.Checked {
+		parts = append(parts, "checked=true")
+	}
+	if m.Disabled {
+		parts = append(parts, "disabled=true")
+	} else if m.Plain {
+		parts = append(parts, "disabled=false")
+	}
+	if m.Refresh || !(opts.OmitFalseRefresh || opts.OmitDefaults) {
+		parts = append(parts, fmt.Sprintf("refresh=%t", m.Refresh))
+	}
+	trim := m.Style.Trim || opts.Trim
+	switch {
+	case trim && opts.OmitDefaults:
+	case !trim && (opts.OmitFalseTrim || opts.OmitDefaults) && strings.TrimSpace(m.Title) == m.Title:
+	default:
+		parts = append(parts, fmt.Sprintf("trim=%t", trim))
+	}
 
-	return strings.Join(parts, " ")
+	if isAlt {
+		parts = append(parts, "alternate=true")
+	}
+
+	parts, err := applyParams(parts, m.Params)
+	if err != nil {
+		return "", fmt.Errorf("menu item %q: %w", m.Title, err)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// applyParams replaces any rendered parameters that are overridden by params
+// in place, then appends the remaining params sorted by name.
+func applyParams(parts []string, params map[string]string) ([]string, error) {
+	if len(params) == 0 {
+		return parts, nil
+	}
+	overridden := map[string]bool{}
+	// The first part is the title.
+	for i := 1; i < len(parts); i++ {
+		name, _, _ := strings.Cut(parts[i], "=")
+		if value, ok := params[name]; ok && name != "alternate" {
+			parts[i] = formatParam(name, value)
+			overridden[name] = true
+		}
+	}
+	var names []string
+	for name := range params {
+		if !overridden[name] && name != "alternate" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validParamName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		parts = append(parts, formatParam(name, params[name]))
+	}
+	return parts, nil
+}
+
+// validParamName reports whether name can be parsed as a parameter name.
+func validParamName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n=|")
+}
+
+func formatParam(name, value string) string {
+	return fmt.Sprintf("%s=%s", name, quoteParamIfNeeded(lineBreakReplacer.Replace(value)))
 }
 
-func (m *MenuItem) renderAlt() string {
+// quoteParam quotes a parameter value so that xbar parses it as a single
+// value. Single quotes are preferred; values containing a single quote are
+// wrapped in double quotes instead, escaping any double quotes and backslashes.
+//
+// Each line of output is a separate menu item, so line breaks are replaced
+// with spaces.
+func quoteParam(s string) string {
+	s = lineBreakReplacer.Replace(s)
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// quoteParamIfNeeded returns s unchanged unless it contains whitespace or
+// quotes, in which case it is quoted with quoteParam.
+func quoteParamIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t'\"") {
+		return quoteParam(s)
+	}
+	return s
+}
+
+func (m *MenuItem) alternate() XbarElement {
 	if m.Alt == nil {
-		return ""
+		return nil
 	}
-	return m.Alt.renderSelf()
+	return m.Alt
 }
 
 func (m *MenuItem) children() []XbarElement {
@@ -314,8 +762,56 @@ func (m *MenuItem) children() []XbarElement {
 // Plugins should follow the Apple Human Interface Guidelines:
 // https://developer.apple.com/design/human-interface-guidelines/macos/menus/menu-anatomy/
 type Plugin struct {
-	Title    *MenuItem
-	Elements []XbarElement
+	// Metadata is written as a header of comments by WriteHeader, if set.
+	Metadata *Metadata
+	// Variables are written as metadata declarations by WriteHeader.
+	Variables []Variable
+	Title     *MenuItem
+	// CycleTitles are additional titles that xbar cycles through in the menu
+	// bar, following Title.
+	CycleTitles []*MenuItem
+	Elements    []XbarElement
+	// Strict causes RunW to return any errors reported by Validate instead of
+	// rendering the plugin.
+	Strict bool
+	// LegacyShell renders ShellActions in the bash="cmd" param0=... format
+	// understood by BitBar and older xbar forks, instead of shell="cmd"
+	// param1=....
+	//
+	// Note that arguments are zero-indexed in the legacy format, so the first
+	// argument is param0 rather than param1.
+	LegacyShell bool
+	// CleanOutput omits parameters that match xbar's defaults, such as
+	// refresh=false, so that a plain item renders as just its title. See
+	// RenderOptions.OmitDefaults.
+	CleanOutput bool
+	// MaxSize is the largest rendered output, in bytes, permitted by Validate.
+	// Zero uses DefaultMaxSize, and a negative value disables the check.
+	MaxSize int
+}
+
+// RenderOptions configure how RenderW renders a plugin, independently of the
+// configuration of its menu items.
+type RenderOptions struct {
+	// LegacyShell renders ShellActions in the legacy bash= format. See
+	// Plugin.LegacyShell.
+	LegacyShell bool
+	// Trim trims whitespace from every title, as if Style.Trim were set on
+	// every menu item.
+	Trim bool
+	// OmitFalseRefresh omits refresh=false from menu items that do not refresh
+	// the plugin, which is xbar's default.
+	OmitFalseRefresh bool
+	// OmitFalseTrim omits trim=false from menu items whose titles have no
+	// leading or trailing whitespace, where trimming has no effect.
+	OmitFalseTrim bool
+	// OmitDefaults omits every parameter whose value matches xbar's default,
+	// including those omitted by OmitFalseRefresh and OmitFalseTrim, as well
+	// as trim=true, dropdown=true, emojize=true and terminal=true.
+	//
+	// disabled=false is still rendered for Plain items, since xbar otherwise
+	// shows items without an action as disabled.
+	OmitDefaults bool
 }
 
 func NewPlugin() *Plugin {
@@ -324,42 +820,288 @@ func NewPlugin() *Plugin {
 	}
 }
 
+func (p *Plugin) WithMetadata(metadata Metadata) *Plugin {
+	p.Metadata = &metadata
+	return p
+}
+
+func (p *Plugin) WithVariables(variables ...Variable) *Plugin {
+	p.Variables = append(p.Variables, variables...)
+	return p
+}
+
+// WithTitles configures the plugin to cycle through multiple titles in the
+// menu bar. The first item replaces Title, so WithText and WithIcon will
+// continue to modify it. Unless the first item has an icon of its own, it
+// keeps the IconImageTemplate style of the Title it replaces, so that
+// NewPlugin's template icon default still applies.
+func (p *Plugin) WithTitles(items ...*MenuItem) *Plugin {
+	if len(items) == 0 {
+		return p
+	}
+	if items[0].Icon == nil && p.Title != nil {
+		items[0].Style.IconImageTemplate = items[0].Style.IconImageTemplate || p.Title.Style.IconImageTemplate
+	}
+	p.Title = items[0]
+	p.CycleTitles = items[1:]
+	return p
+}
+
 func (p *Plugin) WithIcon(icon io.Reader) *Plugin {
 	p.Title = p.Title.WithIcon(icon)
 	return p
 }
 
+func (p *Plugin) WithSFSymbol(name string) *Plugin {
+	p.Title = p.Title.WithSFSymbol(name)
+	return p
+}
+
 func (p *Plugin) WithText(title string) *Plugin {
 	p.Title.Title = title
 	return p
 }
 
+// WithLegacyShell renders shell commands in the legacy bash= format.
+func (p *Plugin) WithLegacyShell() *Plugin {
+	p.LegacyShell = true
+	return p
+}
+
+// WithStrict enables validation of the plugin before it is rendered.
+func (p *Plugin) WithStrict() *Plugin {
+	p.Strict = true
+	return p
+}
+
+// WithCleanOutput omits parameters that match xbar's defaults from the output.
+func (p *Plugin) WithCleanOutput() *Plugin {
+	p.CleanOutput = true
+	return p
+}
+
+// WithMaxSize sets the largest rendered output, in bytes, permitted by
+// Validate.
+func (p *Plugin) WithMaxSize(n int) *Plugin {
+	p.MaxSize = n
+	return p
+}
+
 func (p *Plugin) WithElements(elements ...XbarElement) *Plugin {
 	p.Elements = append(p.Elements, elements...)
 	return p
 }
 
+// Compact removes redundant separators from the plugin's elements, such as
+// those left behind when sections are added conditionally. Consecutive
+// separators are collapsed into one, and leading and trailing separators are
+// removed. Hidden menu items are removed first, so that the separators around
+// them are collapsed as well. Other menu items are left untouched and in order.
+//
+// Submenus cannot contain separators, so only top level elements are affected.
+func (p *Plugin) Compact() *Plugin {
+	var elements []XbarElement
+	for _, el := range p.Elements {
+		if item, ok := el.(*MenuItem); ok && item.Hidden {
+			continue
+		}
+		if _, ok := el.(Separator); ok {
+			if len(elements) == 0 {
+				continue
+			}
+			if _, prevSep := elements[len(elements)-1].(Separator); prevSep {
+				continue
+			}
+		}
+		elements = append(elements, el)
+	}
+	if n := len(elements); n > 0 {
+		if _, ok := elements[n-1].(Separator); ok {
+			elements = elements[:n-1]
+		}
+	}
+	p.Elements = elements
+	return p
+}
+
+// titles returns the Title followed by any CycleTitles that are not hidden.
+// Title is always included, since a plugin must have a title.
+func (p *Plugin) titles() []*MenuItem {
+	titles := []*MenuItem{p.Title}
+	for _, t := range p.CycleTitles {
+		if t == nil || !t.Hidden {
+			titles = append(titles, t)
+		}
+	}
+	return titles
+}
+
+// visibleElements returns the elements that are not hidden. Separators that
+// hidden items leave leading, trailing or next to another separator are dropped
+// too, so that hiding an item leaves no trace in the output. Separators that
+// are redundant regardless of hidden items are kept; see Compact.
+func visibleElements(elements []XbarElement) []XbarElement {
+	var (
+		visible []XbarElement
+		// hidden reports whether an item was hidden since the last visible
+		// element.
+		hidden bool
+	)
+	for _, el := range elements {
+		if item, ok := el.(*MenuItem); ok && item.Hidden {
+			hidden = true
+			continue
+		}
+		if _, ok := el.(Separator); ok && hidden {
+			if len(visible) == 0 {
+				continue
+			}
+			if _, prevSep := visible[len(visible)-1].(Separator); prevSep {
+				continue
+			}
+		}
+		visible = append(visible, el)
+		hidden = false
+	}
+	if n := len(visible); n > 0 && hidden {
+		if _, ok := visible[n-1].(Separator); ok {
+			visible = visible[:n-1]
+		}
+	}
+	return visible
+}
+
+// FilterElements returns the elements for which keep returns true, preserving
+// their order.
+func FilterElements(elements []XbarElement, keep func(XbarElement) bool) []XbarElement {
+	var filtered []XbarElement
+	for _, el := range elements {
+		if keep(el) {
+			filtered = append(filtered, el)
+		}
+	}
+	return filtered
+}
+
 // Run implements the Plugin API of xbar by rendering its configuration to the standard output.
+//
+// If rendering fails, for example because an Icon could not be read, the error
+// is logged and the program exits with a non-zero status.
 func (p *Plugin) Run() {
 	if err := p.RunW(os.Stdout); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// RunContext is like Run, but gives up rendering once ctx is done. See
+// RunWContext for what happens to the abandoned render.
+//
+// If rendering fails or does not complete in time, an error state is shown in
+// the menu bar instead and the error is returned.
+func (p *Plugin) RunContext(ctx context.Context) error {
+	return p.RunWContext(ctx, os.Stdout)
+}
+
+// RunWContext renders the plugin configuration to the specified writer,
+// returning the context's error if ctx is done before rendering completes.
+//
+// Output is buffered until rendering completes, so that a partial menu is never
+// written. On failure an error state is written instead.
+//
+// Rendering itself is not interrupted when ctx is done: it keeps running in a
+// background goroutine until every icon reader returns, and a reader that
+// never returns leaks that goroutine. To release such resources, use readers
+// that are bound to ctx, such as the body of an HTTP request made with ctx.
+//
+// This is provided for testing purposes; in other cases the RunContext
+// function may be more convenient.
+func (p *Plugin) RunWContext(ctx context.Context, w io.Writer) error {
+	var (
+		buf  bytes.Buffer
+		done = make(chan error, 1)
+	)
+	go func() {
+		done <- p.RunW(&buf)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		if renderErr := newErrorPlugin(err).RunW(w); renderErr != nil {
+			return errors.Join(err, renderErr)
+		}
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// newErrorPlugin returns a Plugin that displays err.
+func newErrorPlugin(err error) *Plugin {
+	return NewPlugin().WithText("⚠️").WithElements(
+		NewMenuItem(err.Error()).WithStyle(Style{Color: "red"}),
+	)
+}
+
 // RunW renders the plugin configuration to the specified writer.
 //
 // This is provided for testing purposes; in other cases the Run function may
 // be more convenient.
 func (p *Plugin) RunW(w io.Writer) error {
-	if _, err := fmt.Fprintln(w, p.Title.renderSelf()); err != nil {
-		return err
+	if p.Strict {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	return p.RenderW(w, p.renderOptions())
+}
+
+// renderOptions returns the RenderOptions used by RunW, as configured by the
+// plugin's fields.
+func (p *Plugin) renderOptions() RenderOptions {
+	return RenderOptions{
+		LegacyShell:  p.LegacyShell,
+		OmitDefaults: p.CleanOutput,
+	}
+}
+
+// RenderW renders the plugin configuration to the specified writer using opts
+// in place of the plugin's own rendering configuration, such as LegacyShell.
+// Unlike RunW, the plugin is not validated first even if Strict is set.
+//
+// Each line is rendered as the title followed by any parameters, which always
+// appear in this order:
+//
+//	key length color bgcolor font size ansi emojize md tooltip
+//	href | terminal shell param1... | bash param0...
+//	sfimage | image | templateImage
+//	dropdown checked disabled refresh trim alternate
+//
+// followed by any MenuItem.Params not already rendered, sorted by name.
+// Parameters are only rendered when set, except for refresh and trim which
+// are rendered as false unless omitted by opts.
+//
+// An error is returned for Params with names that could not be parsed, e.g.
+// because they contain spaces.
+func (p *Plugin) RenderW(w io.Writer, opts RenderOptions) error {
+	for _, t := range p.titles() {
+		title, err := t.renderSelf(opts, false)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, title); err != nil {
+			return err
+		}
 	}
-	if len(p.Elements) > 0 {
+	if elements := visibleElements(p.Elements); len(elements) > 0 {
 		if _, err := fmt.Fprintln(w, "---"); err != nil {
 			return err
 		}
-		for _, item := range p.Elements {
-			if err := printElement(w, item, 0); err != nil {
+		for _, item := range elements {
+			if err := printElement(w, item, 0, opts, false); err != nil {
 				return err
 			}
 		}
@@ -367,22 +1109,27 @@ func (p *Plugin) RunW(w io.Writer) error {
 	return nil
 }
 
-func printElement(w io.Writer, el XbarElement, level int) error {
+func printElement(w io.Writer, el XbarElement, level int, opts RenderOptions, isAlt bool) error {
+	if item, ok := el.(*MenuItem); ok && item.Hidden {
+		return nil
+	}
 	prefix := strings.Repeat("--", level)
-	if _, err := fmt.Fprintf(w, "%s%s\n", prefix, el.renderSelf()); err != nil {
+	self, err := el.renderSelf(opts, isAlt)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s%s\n", prefix, self); err != nil {
 		return err
 	}
 	// It's important that the child items come before the alt item, otherwise they'll
-	// be attached to the alt.
+	// be attached to the alt. The alt's own children follow it for the same reason.
 	for _, child := range el.children() {
-		if err := printElement(w, child, level+1); err != nil {
+		if err := printElement(w, child, level+1, opts, false); err != nil {
 			return err
 		}
 	}
-	if alt := el.renderAlt(); alt != "" {
-		if _, err := fmt.Fprintf(w, "%s%s alternate=true\n", prefix, alt); err != nil {
-			return err
-		}
+	if alt := el.alternate(); alt != nil && !isAlt {
+		return printElement(w, alt, level, opts, true)
 	}
 	return nil
 }
@@ -2,16 +2,884 @@ package xbargo_test
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
 
 	"github.com/jlegrone/xbargo"
 )
 
+func render(t *testing.T, p *xbargo.Plugin) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.RunW(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// renderAction renders a lone title item with the given action and returns
+// the action's params.
+func renderAction(t *testing.T, a xbargo.Action) string {
+	t.Helper()
+	line := render(t, &xbargo.Plugin{Title: xbargo.NewMenuItem("").WithAction(a)})
+	params, ok := strings.CutPrefix(line, "| ")
+	if !ok {
+		t.Fatalf("unexpected output %q", line)
+	}
+	params, ok = strings.CutSuffix(params, " refresh=false trim=false\n")
+	if !ok {
+		t.Fatalf("unexpected output %q", line)
+	}
+	return params
+}
+
+func TestMenuItem_titleEscaping(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"pipe", "CPU 50% | 4 cores", "CPU 50% ｜ 4 cores| refresh=false trim=false\n"},
+		{"newline", "line one\nline two", "line one line two| refresh=false trim=false\n"},
+		{"carriage return", "line one\r\nline two\rline three", "line one line two line three| refresh=false trim=false\n"},
+		{"tab", "col1\tcol2", "col1\tcol2| refresh=false trim=false\n"},
+		{"leading dashes", "--verbose", "\u2010-verbose| refresh=false trim=false\n"},
+		{"separator", "---", "\u2010--| refresh=false trim=false\n"},
+		{"inner dashes", "a -- b", "a -- b| refresh=false trim=false\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := render(t, &xbargo.Plugin{Title: xbargo.NewMenuItem(tc.title)})
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("alt", func(t *testing.T) {
+		got := render(t, &xbargo.Plugin{
+			Title: xbargo.NewMenuItem("Title"),
+			Elements: []xbargo.XbarElement{
+				xbargo.NewMenuItem("a|b").WithAlt(xbargo.NewMenuItem("c|d\ne")),
+			},
+		})
+		want := "Title| refresh=false trim=false\n" +
+			"---\n" +
+			"a｜b| refresh=false trim=false\n" +
+			"c｜d e| refresh=false trim=false alternate=true\n"
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("dashes", func(t *testing.T) {
+		p := xbargo.NewPlugin().WithText("Title").WithElements(
+			xbargo.NewMenuItem("Parent").WithSubMenu(xbargo.NewMenuItem("-n")),
+			xbargo.NewMenuItem("--verbose"),
+			xbargo.NewMenuItem("---"),
+		)
+		out := render(t, p)
+		want := "Title| refresh=false trim=false\n" +
+			"---\n" +
+			"Parent| refresh=false trim=false\n" +
+			"--\u2010n| refresh=false trim=false\n" +
+			"\u2010-verbose| refresh=false trim=false\n" +
+			"\u2010--| refresh=false trim=false\n"
+		if out != want {
+			t.Errorf("got %q, want %q", out, want)
+		}
+		parsed, err := xbargo.Parse(strings.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed.Elements) != 3 {
+			t.Errorf("got %d elements, want 3", len(parsed.Elements))
+		}
+	})
+}
+
 func userHomeDir() string {
 	// hardcoded for testing purposes, normally we'd use os.UserHomeDir()
 	return "/tmp/xbargo_test"
 }
 
+func TestShellAction_paramQuoting(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action xbargo.ShellAction
+		want   string
+	}{
+		{
+			name:   "spaces",
+			action: xbargo.NewShellAction("echo", "hello world"),
+			want:   `terminal=false shell='echo' param1='hello world'`,
+		},
+		{
+			name:   "single quote",
+			action: xbargo.NewShellAction("echo", "it's broken"),
+			want:   `terminal=false shell='echo' param1="it's broken"`,
+		},
+		{
+			name:   "double quote",
+			action: xbargo.NewShellAction("echo", `say "hi"`),
+			want:   `terminal=false shell='echo' param1='say "hi"'`,
+		},
+		{
+			name:   "both quotes",
+			action: xbargo.NewShellAction("echo", `it's "quoted" \o/`),
+			want:   `terminal=false shell='echo' param1="it's \"quoted\" \\o/"`,
+		},
+		{
+			name:   "single quote and backslash",
+			action: xbargo.NewShellAction("echo", `it's C:\dir`),
+			want:   `terminal=false shell='echo' param1="it's C:\\dir"`,
+		},
+		{
+			name:   "line breaks",
+			action: xbargo.NewShellAction("echo", "one\ntwo\r\nthree"),
+			want:   `terminal=false shell='echo' param1='one two three'`,
+		},
+		{
+			name:   "command",
+			action: xbargo.NewShellAction("/Applications/My App.app/run\tcafé"),
+			want:   "terminal=false shell='/Applications/My App.app/run\tcafé'",
+		},
+		{
+			name:   "dollar",
+			action: xbargo.NewShellAction("echo", "$HOME"),
+			want:   `terminal=false shell='echo' param1='$HOME'`,
+		},
+		{
+			name:   "copy",
+			action: xbargo.NewCopyAction("it's"),
+			want:   `terminal=false shell='/bin/bash' param1='-c' param2='echo aXQncw== | base64 --decode | pbcopy'`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderAction(t, tc.action); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewCopyAction(t *testing.T) {
+	for _, text := range []string{
+		"/tmp/xbargo_test",
+		"line one\nline two\n",
+		"$(rm -rf ~) `whoami` $HOME",
+		`it's "quoted" \n`,
+		"-e -n",
+		"🐌 émoji",
+	} {
+		t.Run(text, func(t *testing.T) {
+			action := xbargo.NewCopyAction(text)
+			if action.Command != "/bin/bash" || len(action.Args) != 2 || action.Args[0] != "-c" {
+				t.Fatalf("unexpected action %+v", action)
+			}
+			script := action.Args[1]
+			encoded, ok := strings.CutPrefix(script, "echo ")
+			if !ok {
+				t.Fatalf("unexpected script %q", script)
+			}
+			encoded, ok = strings.CutSuffix(encoded, " | base64 --decode | pbcopy")
+			if !ok {
+				t.Fatalf("unexpected script %q", script)
+			}
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != text {
+				t.Errorf("got %q, want %q", decoded, text)
+			}
+		})
+	}
+}
+
+func TestNewHrefActionURL(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action xbargo.HrefAction
+		want   string
+	}{
+		{
+			name: "query",
+			action: xbargo.NewHrefActionURL("https://github.com/jlegrone/xbargo/pulls", url.Values{
+				"q": {"is:open author:me & more"},
+			}),
+			want: "href=https://github.com/jlegrone/xbargo/pulls?q=is%3Aopen+author%3Ame+%26+more",
+		},
+		{
+			name: "existing query",
+			action: xbargo.NewHrefActionURL("https://example.com/search?lang=en", url.Values{
+				"q": {"café ☕"},
+			}),
+			want: "href=https://example.com/search?lang=en&q=caf%C3%A9+%E2%98%95",
+		},
+		{
+			name: "existing query order",
+			action: xbargo.NewHrefActionURL("https://example.com/search?z=1&a=2&z=3", url.Values{
+				"q": {"x"},
+			}),
+			want: "href=https://example.com/search?z=1&a=2&z=3&q=x",
+		},
+		{
+			name: "existing key",
+			action: xbargo.NewHrefActionURL("https://example.com/search?q=a", url.Values{
+				"q": {"b"},
+			}),
+			want: "href=https://example.com/search?q=a&q=b",
+		},
+		{
+			name:   "fragment",
+			action: xbargo.NewHrefActionURL("https://example.com/docs#usage", url.Values{"v": {"2"}}),
+			want:   "href=https://example.com/docs?v=2#usage",
+		},
+		{
+			name:   "encoded path",
+			action: xbargo.NewHrefActionURL("https://x.com/a%2Fb c?x=1", url.Values{"q": {"z"}}),
+			want:   "href='https://x.com/a%2Fb c?x=1&q=z'",
+		},
+		{
+			name:   "trailing question mark",
+			action: xbargo.NewHrefActionURL("https://example.com/search?", url.Values{"q": {"x"}}),
+			want:   "href=https://example.com/search?q=x",
+		},
+		{
+			name:   "empty query",
+			action: xbargo.NewHrefActionURL("https://example.com/search?lang=en", nil),
+			want:   "href=https://example.com/search?lang=en",
+		},
+		{
+			name:   "unparseable",
+			action: xbargo.NewHrefActionURL("http://[::1%zz]/search", url.Values{"q": {"x"}}),
+			want:   "href=http://[::1%zz]/search?q=x",
+		},
+		{
+			name:   "unparseable with query",
+			action: xbargo.NewHrefActionURL("http://[::1%zz]/search?lang=en", url.Values{"q": {"x"}}),
+			want:   "href=http://[::1%zz]/search?lang=en&q=x",
+		},
+		{
+			name:   "mailto",
+			action: xbargo.NewMailtoAction("me@example.com", "Status: down & out", "Hi,\nIt's broken 😢"),
+			want:   "href=mailto:me@example.com?subject=Status%3A%20down%20%26%20out&body=Hi%2C%0AIt%27s%20broken%20%F0%9F%98%A2",
+		},
+		{
+			name:   "mailto without subject",
+			action: xbargo.NewMailtoAction("me@example.com", "", ""),
+			want:   "href=mailto:me@example.com",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderAction(t, tc.action); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRefreshAction(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action xbargo.HrefAction
+		want   string
+	}{
+		{
+			name:   "plugin",
+			action: xbargo.NewRefreshAction("my plugin.1m.sh"),
+			want:   "href=xbar://app.xbarapp.com/refreshPlugin?path=my+plugin.1m.sh",
+		},
+		{
+			name:   "all",
+			action: xbargo.NewRefreshAllAction(),
+			want:   "href=xbar://app.xbarapp.com/refreshAllPlugins",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderAction(t, tc.action); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewAppleScriptAction(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action xbargo.ShellAction
+		want   string
+	}{
+		{
+			name:   "single line",
+			action: xbargo.NewAppleScriptAction(`tell application "Finder" to activate`),
+			want:   `terminal=false shell='osascript' param1='-e' param2='tell application "Finder" to activate'`,
+		},
+		{
+			name: "multi-line",
+			action: xbargo.NewAppleScriptAction("tell application \"Music\"\r\n\n\tplay\nend tell\n").
+				WithTerminal(),
+			want: `terminal=true shell='osascript' param1='-e' param2='tell application "Music"' param3='-e' param4='	play' param5='-e' param6='end tell'`,
+		},
+		{
+			name:   "notification",
+			action: xbargo.NewNotificationAction("Example", "Thanks for clicking!", `It's a "notification"`),
+			want:   `terminal=false shell='osascript' param1='-e' param2="display notification \"It's a \\\"notification\\\"\" with title \"Example\" subtitle \"Thanks for clicking!\""`,
+		},
+		{
+			name:   "multi-line notification",
+			action: xbargo.NewNotificationAction("Build", "", "line1\nline2\r\n"),
+			want:   `terminal=false shell='osascript' param1='-e' param2='display notification "line1\nline2\r\n" with title "Build"'`,
+		},
+		{
+			name:   "notification without title",
+			action: xbargo.NewNotificationAction("", "", "Done"),
+			want:   `terminal=false shell='osascript' param1='-e' param2='display notification "Done"'`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderAction(t, tc.action); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewOpenFileAction(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action xbargo.ShellAction
+		want   string
+	}{
+		{
+			name:   "open",
+			action: xbargo.NewOpenFileAction("/tmp/xbargo_test"),
+			want:   `terminal=false shell='open' param1='/tmp/xbargo_test'`,
+		},
+		{
+			name:   "open with spaces",
+			action: xbargo.NewOpenFileAction("/Users/me/My Documents/it's here.txt"),
+			want:   `terminal=false shell='open' param1="/Users/me/My Documents/it's here.txt"`,
+		},
+		{
+			name:   "reveal",
+			action: xbargo.NewRevealAction("/Applications/Utilities/Activity Monitor.app"),
+			want:   `terminal=false shell='open' param1='-R' param2='/Applications/Utilities/Activity Monitor.app'`,
+		},
+		{
+			name:   "open with app",
+			action: xbargo.NewOpenWithAppAction("/tmp/release notes.md", "com.apple.TextEdit").WithTerminal(),
+			want:   `terminal=true shell='open' param1='-b' param2='com.apple.TextEdit' param3='/tmp/release notes.md'`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderAction(t, tc.action); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestMenuItem_paramOrder locks the canonical parameter order documented on
+// Plugin.RenderW, so that golden output is stable across versions.
+func TestMenuItem_paramOrder(t *testing.T) {
+	emojize := false
+	newItem := func(title string) *xbargo.MenuItem {
+		return xbargo.NewMenuItem(title).
+			WithStyle(xbargo.Style{
+				MaxLength:         10,
+				Color:             "black",
+				ColorDark:         "white",
+				BackgroundColor:   "red",
+				Font:              "Menlo",
+				Size:              12,
+				IconImageTemplate: true,
+				Trim:              true,
+				ANSI:              true,
+				Emojize:           &emojize,
+				Markdown:          true,
+			}).
+			WithShortcut("k", xbargo.CommandKey).
+			WithTooltip("tip").
+			WithShell("echo", "hello").
+			WithIcon(strings.NewReader("icon")).
+			WithDropdown(true).
+			WithChecked(true).
+			WithDisabled().
+			WithRefresh()
+	}
+	p := xbargo.NewPlugin().WithText("Title").WithElements(
+		newItem("Item").WithAlt(newItem("Alt")),
+	)
+	params := "key=CmdOrCtrl+k length=10 color=black,white bgcolor=red font=Menlo size=12 ansi=true emojize=false md=true tooltip=tip " +
+		`terminal=false shell='echo' param1='hello' templateImage=aWNvbg== dropdown=true checked=true disabled=true refresh=true trim=true`
+	want := "Title| refresh=false trim=false\n---\nItem| " + params + "\nAlt| " + params + " alternate=true\n"
+	if got := render(t, p); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlugin_RenderW(t *testing.T) {
+	newPlugin := func() *xbargo.Plugin {
+		return xbargo.NewPlugin().WithText("Title").WithElements(
+			xbargo.NewMenuItem("Say").WithShell("say", "hi"),
+			xbargo.NewMenuItem(" padded ").WithRefresh(),
+		)
+	}
+	for _, tc := range []struct {
+		name string
+		opts xbargo.RenderOptions
+		want string
+	}{
+		{
+			name: "defaults",
+			want: "Title| refresh=false trim=false\n---\n" +
+				"Say| terminal=false shell='say' param1='hi' refresh=false trim=false\n" +
+				" padded | refresh=true trim=false\n",
+		},
+		{
+			name: "legacy shell",
+			opts: xbargo.RenderOptions{LegacyShell: true},
+			want: "Title| refresh=false trim=false\n---\n" +
+				"Say| terminal=false bash='say' param0='hi' refresh=false trim=false\n" +
+				" padded | refresh=true trim=false\n",
+		},
+		{
+			name: "trim",
+			opts: xbargo.RenderOptions{Trim: true, OmitFalseRefresh: true},
+			want: "Title| trim=true\n---\n" +
+				"Say| terminal=false shell='say' param1='hi' trim=true\n" +
+				" padded | refresh=true trim=true\n",
+		},
+		{
+			name: "omit false",
+			opts: xbargo.RenderOptions{OmitFalseRefresh: true, OmitFalseTrim: true},
+			want: "Title|\n---\n" +
+				"Say| terminal=false shell='say' param1='hi'\n" +
+				// trim=false is still needed to preserve the whitespace.
+				" padded | refresh=true trim=false\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := newPlugin().RenderW(&buf, tc.opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+
+	// RunW renders with the plugin's own configuration.
+	p := newPlugin().WithLegacyShell()
+	var buf bytes.Buffer
+	if err := p.RenderW(&buf, xbargo.RenderOptions{LegacyShell: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(t, p); got != buf.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", got, buf.String())
+	}
+}
+
+func TestPlugin_WithLegacyShell(t *testing.T) {
+	newPlugin := func() *xbargo.Plugin {
+		return xbargo.NewPlugin().WithText("Title").WithElements(
+			xbargo.NewMenuItem("Say").WithAction(xbargo.NewShellAction("say", "hello", "it's me").WithTerminal()),
+		)
+	}
+	for _, tc := range []struct {
+		name   string
+		plugin *xbargo.Plugin
+		want   string
+	}{
+		{
+			name:   "default",
+			plugin: newPlugin(),
+			want:   `Say| terminal=true shell='say' param1='hello' param2="it's me" refresh=false trim=false`,
+		},
+		{
+			name:   "legacy",
+			plugin: newPlugin().WithLegacyShell(),
+			want:   `Say| terminal=true bash='say' param0='hello' param1="it's me" refresh=false trim=false`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := render(t, tc.plugin)
+			want := "Title| refresh=false trim=false\n---\n" + tc.want + "\n"
+			if got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestStyle_trim(t *testing.T) {
+	got := render(t, &xbargo.Plugin{
+		Title: xbargo.NewMenuItem("  padded  ").WithStyle(xbargo.Style{Trim: true}),
+		Elements: []xbargo.XbarElement{
+			xbargo.NewMenuItem(" a ").
+				WithStyle(xbargo.Style{Trim: true}).
+				WithAlt(xbargo.NewMenuItem(" b ").WithStyle(xbargo.Style{Trim: true})),
+		},
+	})
+	want := "  padded  | refresh=false trim=true\n" +
+		"---\n" +
+		" a | refresh=false trim=true\n" +
+		" b | refresh=false trim=true alternate=true\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStyle_fontAndSize(t *testing.T) {
+	style := xbargo.Style{MaxLength: 10, Color: "red", Font: "Menlo", Size: 12}
+	got := render(t, &xbargo.Plugin{
+		Title: xbargo.NewMenuItem("Title").WithStyle(style),
+		Elements: []xbargo.XbarElement{
+			xbargo.NewMenuItem("Parent").WithSubMenu(
+				xbargo.NewMenuItem("Child").WithStyle(xbargo.Style{Font: "Helvetica Neue"}),
+			),
+		},
+	})
+	want := "Title| length=10 color=red font=Menlo size=12 refresh=false trim=false\n" +
+		"---\n" +
+		"Parent| refresh=false trim=false\n" +
+		"--Child| font='Helvetica Neue' refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStyle_ansiAndEmojize(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("\x1b[31mred\x1b[0m").WithANSI(),
+		xbargo.NewMenuItem("default :smile:"),
+		xbargo.NewMenuItem("enabled :smile:").WithEmojize(true),
+		xbargo.NewMenuItem("12:30:45").WithEmojize(false),
+	))
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"\x1b[31mred\x1b[0m| ansi=true refresh=false trim=false\n" +
+		"default :smile:| refresh=false trim=false\n" +
+		"enabled :smile:| emojize=true refresh=false trim=false\n" +
+		"12:30:45| emojize=false refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStyle_markdown(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("**3** _failing_ [checks](https://example.com) | `main`").
+			WithStyle(xbargo.Style{Markdown: true}),
+	))
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"**3** _failing_ [checks](https://example.com) ｜ `main`| md=true refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMenuItem_WithColors(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("Both").WithColors("#000000", "#ffffff"),
+		xbargo.NewMenuItem("Light").WithStyle(xbargo.Style{Color: "red"}),
+		xbargo.NewMenuItem("Dark only").WithStyle(xbargo.Style{ColorDark: "red"}),
+	))
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"Both| color=#000000,#ffffff refresh=false trim=false\n" +
+		"Light| color=red refresh=false trim=false\n" +
+		"Dark only| refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMenuItem_WithColorValue(t *testing.T) {
+	// Interpolate from red to green
+	var got []string
+	for i := 0; i <= 4; i++ {
+		c := color.RGBA{R: uint8(255 - i*255/4), G: uint8(i * 255 / 4), A: 0xff}
+		got = append(got, xbargo.NewMenuItem("").WithColorValue(c).Style.Color)
+	}
+	want := []string{"#ff0000", "#c03f00", "#807f00", "#40bf00", "#00ff00"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		color color.Color
+		want  string
+	}{
+		{"padding", color.RGBA{R: 0x01, G: 0x02, B: 0x03, A: 0xff}, "#010203"},
+		{"gray", color.Gray{Y: 0x7f}, "#7f7f7f"},
+		// Premultiplied alpha is discarded without darkening the color
+		{"translucent", color.RGBA{R: 0x80, A: 0x80}, "#ff0000"},
+		{"transparent", color.Transparent, "#000000"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := xbargo.NewMenuItem("").WithColorValue(tc.color).Style.Color; got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_WithBackgroundColor(t *testing.T) {
+	p := xbargo.NewPlugin().WithText("Alerts").WithElements(
+		xbargo.NewMenuItem("CRITICAL: disk full").
+			WithStyle(xbargo.Style{Color: "white"}).
+			WithBackgroundColor("red"),
+		xbargo.NewMenuItem("Warning: high load").
+			WithColorValue(color.RGBA{R: 0xff, G: 0xa5, A: 0xff}).
+			WithBackgroundColorValue(color.Gray{Y: 0x20}),
+		xbargo.NewMenuItem("OK").WithStyle(xbargo.Style{Color: "green"}),
+	)
+	want := strings.Join([]string{
+		"Alerts| refresh=false trim=false",
+		"---",
+		"CRITICAL: disk full| color=white bgcolor=red refresh=false trim=false",
+		"Warning: high load| color=#ffa500 bgcolor=#202020 refresh=false trim=false",
+		"OK| color=green refresh=false trim=false",
+		"",
+	}, "\n")
+	if got := render(t, p); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMenuItem_WithParams(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		item *xbargo.MenuItem
+		want string
+	}{
+		{
+			name: "custom",
+			item: xbargo.NewMenuItem("Inbox").
+				WithStyle(xbargo.Style{Color: "red"}).
+				WithParams(map[string]string{"badge": "3 new", "webview": "true"}),
+			want: `Inbox| color=red refresh=false trim=false badge='3 new' webview=true`,
+		},
+		{
+			name: "override",
+			item: xbargo.NewMenuItem("Inbox").
+				WithStyle(xbargo.Style{Color: "red"}).
+				WithShell("open", "-a", "Mail").
+				WithParams(map[string]string{"color": "#00ff00", "terminal": "true", "param2": "Mail Beta"}),
+			want: `Inbox| color=#00ff00 terminal=true shell='open' param1='-a' param2='Mail Beta' refresh=false trim=false`,
+		},
+		{
+			name: "merged",
+			item: xbargo.NewMenuItem("Inbox").
+				WithParams(map[string]string{"b": "1", "a": "1"}).
+				WithParams(map[string]string{"b": "2", "alternate": "true"}),
+			want: `Inbox| refresh=false trim=false a=1 b=2`,
+		},
+		{
+			name: "alternate",
+			item: xbargo.NewMenuItem("Inbox").WithAlt(
+				xbargo.NewMenuItem("Archive").WithParams(map[string]string{"zz": "1", "alternate": "false"}),
+			),
+			want: "Inbox| refresh=false trim=false\nArchive| refresh=false trim=false alternate=true zz=1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(tc.item))
+			if want := "Title| refresh=false trim=false\n---\n" + tc.want + "\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	for _, name := range []string{"bad name", "a=b", ""} {
+		t.Run(fmt.Sprintf("invalid %q", name), func(t *testing.T) {
+			p := xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("Item").WithParams(map[string]string{name: "1"}),
+			)
+			var buf bytes.Buffer
+			err := p.RunW(&buf)
+			if want := fmt.Sprintf("menu item \"Item\": invalid parameter name %q", name); err == nil || err.Error() != want {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_WithTooltip(t *testing.T) {
+	got := render(t, &xbargo.Plugin{
+		Title: xbargo.NewMenuItem("⚠️").WithTooltip("Error: it's down!\nRetrying soon."),
+		Elements: []xbargo.XbarElement{
+			xbargo.NewMenuItem("Status").WithTooltip("ok"),
+		},
+	})
+	want := "⚠️| tooltip=\"Error: it's down! Retrying soon.\" refresh=false trim=false\n" +
+		"---\n" +
+		"Status| tooltip=ok refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStyle_maxLengthTooltip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		item *xbargo.MenuItem
+		want string
+	}{
+		{
+			name: "truncated",
+			item: xbargo.NewMenuItem("Crème brûlée 🍮").WithStyle(xbargo.Style{MaxLength: 12}),
+			want: `Crème brûlée 🍮| length=12 tooltip='Crème brûlée 🍮'`,
+		},
+		{
+			name: "at limit",
+			item: xbargo.NewMenuItem("Crème brûlée 🍮").WithStyle(xbargo.Style{MaxLength: 14}),
+			want: `Crème brûlée 🍮| length=14`,
+		},
+		{
+			name: "escaped",
+			item: xbargo.NewMenuItem("a|bcdefghijk").WithStyle(xbargo.Style{MaxLength: 5}),
+			want: `a｜bcdefghijk| length=5 tooltip=a｜bcdefghijk`,
+		},
+		{
+			name: "explicit tooltip",
+			item: xbargo.NewMenuItem("Crème brûlée 🍮").WithStyle(xbargo.Style{MaxLength: 5}).WithTooltip("Dessert"),
+			want: `Crème brûlée 🍮| length=5 tooltip=Dessert`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := render(t, &xbargo.Plugin{Title: tc.item})
+			if want := tc.want + " refresh=false trim=false\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_WithSFSymbol(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Rain").
+		// SFSymbol takes priority over Icon
+		WithIcon(bytes.NewReader(beakerImage)).
+		WithSFSymbol("cloud.rain"))
+	want := "Rain| sfimage=cloud.rain refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestPlugin_RunW_sharedIcon(t *testing.T) {
+	icon := bytes.NewReader(beakerImage)
+	p := xbargo.NewPlugin().WithStrict().WithText("Title").WithElements(
+		xbargo.NewMenuItem("a").WithIcon(icon),
+		xbargo.NewMenuItem("b").WithIcon(icon),
+	)
+	param := "image=" + base64.StdEncoding.EncodeToString(beakerImage)
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"a| " + param + " refresh=false trim=false\n" +
+		"b| " + param + " refresh=false trim=false\n"
+	// Rendering twice checks that the reader is left where it started.
+	for i := 0; i < 2; i++ {
+		if got := render(t, p); got != want {
+			t.Errorf("render %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestPlugin_RunW_iconError(t *testing.T) {
+	errBroken := errors.New("broken stream")
+	for _, tc := range []struct {
+		name   string
+		plugin *xbargo.Plugin
+	}{
+		{"title", xbargo.NewPlugin().WithIcon(errReader{errBroken})},
+		{"submenu", xbargo.NewPlugin().WithElements(
+			xbargo.NewMenuItem("Parent").WithSubMenu(
+				xbargo.NewMenuItem("Child").WithIcon(errReader{errBroken}),
+			),
+		)},
+		{"alt", xbargo.NewPlugin().WithElements(
+			xbargo.NewMenuItem("Item").WithAlt(
+				xbargo.NewMenuItem("Alt").WithIcon(errReader{errBroken}),
+			),
+		)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.plugin.RunW(&bytes.Buffer{})
+			if !errors.Is(err, errBroken) {
+				t.Errorf("got error %v, want %v", err, errBroken)
+			}
+		})
+	}
+}
+
+func TestMenuItem_WithChecked(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Settings").WithElements(
+		xbargo.NewMenuItem("Appearance").WithSubMenu(
+			xbargo.NewMenuItem("Dark Mode").WithChecked(true).
+				WithAlt(xbargo.NewMenuItem("Dark Mode (Auto)").WithChecked(true)),
+			xbargo.NewMenuItem("Light Mode").WithChecked(false),
+		),
+	))
+	want := "Settings| refresh=false trim=false\n" +
+		"---\n" +
+		"Appearance| refresh=false trim=false\n" +
+		"--Dark Mode| checked=true refresh=false trim=false\n" +
+		"--Dark Mode (Auto)| checked=true refresh=false trim=false alternate=true\n" +
+		"--Light Mode| refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMenuItem_WithDisabled(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("Header").WithDisabled(),
+		xbargo.NewMenuItem("Open").WithHref("https://example.com").WithDisabled(),
+		xbargo.NewMenuItem("Run").WithShell("echo", "hi").WithDisabled(),
+	))
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"Header| disabled=true refresh=false trim=false\n" +
+		"Open| href=https://example.com disabled=true refresh=false trim=false\n" +
+		"Run| terminal=false shell='echo' param1='hi' disabled=true refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMenuItem_WithPlain(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("Section Header").WithPlain(),
+		xbargo.NewMenuItem("Disabled wins").WithPlain().WithDisabled(),
+	))
+	want := "Title| refresh=false trim=false\n" +
+		"---\n" +
+		"Section Header| disabled=false refresh=false trim=false\n" +
+		"Disabled wins| disabled=true refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 // Example of how to run shell commands when menu items are clicked.
 func ExamplePlugin_runShell() {
 	xbargo.NewPlugin().WithText("🐌").WithElements(
@@ -47,16 +915,16 @@ func ExamplePlugin_runShell() {
 	// Output:
 	// 🐌| refresh=false trim=false
 	// ---
-	// 🗣️ Say Hello| terminal=false shell="say" param1='hello' param2='world' refresh=false trim=false
-	// 🗣️ Say Goodbye| terminal=false shell="say" param1='goodbye' param2='world' refresh=false trim=false alternate=true
+	// 🗣️ Say Hello| terminal=false shell='say' param1='hello' param2='world' refresh=false trim=false
+	// 🗣️ Say Goodbye| terminal=false shell='say' param1='goodbye' param2='world' refresh=false trim=false alternate=true
 	// ---
-	// 🔋 Battery Preferences| key=shift+b terminal=false shell="open" param1='-b' param2='com.apple.systempreferences' param3='/System/Library/PreferencePanes/Battery.prefPane' refresh=false trim=false
+	// 🔋 Battery Preferences| key=shift+b terminal=false shell='open' param1='-b' param2='com.apple.systempreferences' param3='/System/Library/PreferencePanes/Battery.prefPane' refresh=false trim=false
 	// ---
 	// 🏠 Home Directory| refresh=false trim=false
-	// --View Tree| terminal=true shell="tree" param1='-d' param2='-L' param3='1' param4='/tmp/xbargo_test' refresh=false trim=false
-	// --Copy Path| key=CmdOrCtrl+c terminal=false shell="/bin/bash" param1='-c' param2='echo -n /tmp/xbargo_test | pbcopy' refresh=false trim=false
+	// --View Tree| terminal=true shell='tree' param1='-d' param2='-L' param3='1' param4='/tmp/xbargo_test' refresh=false trim=false
+	// --Copy Path| key=CmdOrCtrl+c terminal=false shell='/bin/bash' param1='-c' param2='echo L3RtcC94YmFyZ29fdGVzdA== | base64 --decode | pbcopy' refresh=false trim=false
 	// ---
-	// ℹ️ Send Notification| key=ctrl+OptionOrAlt+n terminal=false shell="osascript" param1='-e' param2='display notification "This is a notification" with title "Example" subtitle "Thanks for clicking!"' refresh=false trim=false
+	// ℹ️ Send Notification| key=ctrl+OptionOrAlt+n terminal=false shell='osascript' param1='-e' param2='display notification "This is a notification" with title "Example" subtitle "Thanks for clicking!"' refresh=false trim=false
 }
 
 // Example of how to include alternate items that replace their parent item when the Option key is pressed.
@@ -111,6 +979,125 @@ func ExamplePlugin_submenus() {
 	// ----Honeydew| refresh=false trim=false
 }
 
+func TestMenuItem_WithAlt_subMenu(t *testing.T) {
+	p := xbargo.NewPlugin().WithText("Title").WithElements(
+		xbargo.NewMenuItem("Servers").WithSubMenu(
+			xbargo.NewMenuItem("Production").WithSubMenu(
+				xbargo.NewMenuItem("Status"),
+			).WithAlt(
+				xbargo.NewMenuItem("Production (admin)").WithSubMenu(
+					xbargo.NewMenuItem("Restart").WithSubMenu(
+						xbargo.NewMenuItem("Confirm"),
+					),
+					xbargo.NewMenuItem("Logs"),
+				),
+			),
+			xbargo.NewMenuItem("Staging"),
+		),
+	)
+	want := strings.Join([]string{
+		"Title| refresh=false trim=false",
+		"---",
+		"Servers| refresh=false trim=false",
+		"--Production| refresh=false trim=false",
+		"----Status| refresh=false trim=false",
+		"--Production (admin)| refresh=false trim=false alternate=true",
+		"----Restart| refresh=false trim=false",
+		"------Confirm| refresh=false trim=false",
+		"----Logs| refresh=false trim=false",
+		"--Staging| refresh=false trim=false",
+		"",
+	}, "\n")
+	if got := render(t, p); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlugin_WithCleanOutput(t *testing.T) {
+	newPlugin := func() *xbargo.Plugin {
+		return xbargo.NewPlugin().WithText("Submenu").WithElements(
+			xbargo.NewMenuItem("Places").WithSubMenu(
+				xbargo.NewMenuItem("London"),
+				xbargo.NewMenuItem("Paris").WithRefresh(),
+			),
+			xbargo.Separator{},
+			xbargo.NewMenuItem("Fruit").WithSubMenu(
+				xbargo.NewMenuItem("Melon").WithSubMenu(
+					xbargo.NewMenuItem(" Watermelon "),
+					xbargo.NewMenuItem("Honeydew").WithStyle(xbargo.Style{Trim: true}),
+				),
+			),
+		)
+	}
+	verbose := strings.Join([]string{
+		"Submenu| refresh=false trim=false",
+		"---",
+		"Places| refresh=false trim=false",
+		"--London| refresh=false trim=false",
+		"--Paris| refresh=true trim=false",
+		"---",
+		"Fruit| refresh=false trim=false",
+		"--Melon| refresh=false trim=false",
+		"---- Watermelon | refresh=false trim=false",
+		"----Honeydew| refresh=false trim=true",
+		"",
+	}, "\n")
+	clean := strings.Join([]string{
+		"Submenu|",
+		"---",
+		"Places|",
+		"--London|",
+		"--Paris| refresh=true",
+		"---",
+		"Fruit|",
+		"--Melon|",
+		"---- Watermelon | trim=false",
+		"----Honeydew|",
+		"",
+	}, "\n")
+	if got := render(t, newPlugin()); got != verbose {
+		t.Errorf("got:\n%s\nwant:\n%s", got, verbose)
+	}
+	if got := render(t, newPlugin().WithCleanOutput()); got != clean {
+		t.Errorf("got:\n%s\nwant:\n%s", got, clean)
+	}
+}
+
+func TestPlugin_WithCleanOutput_defaults(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		item *xbargo.MenuItem
+		want string
+	}{
+		{"default", xbargo.NewMenuItem("Hello"), "Hello|"},
+		{
+			name: "explicit defaults",
+			item: xbargo.NewMenuItem("Hello").
+				WithStyle(xbargo.Style{Trim: true}).
+				WithDropdown(true).
+				WithEmojize(true).
+				WithAction(xbargo.NewShellAction("say", "hi").WithTerminal()),
+			want: "Hello| shell='say' param1='hi'",
+		},
+		{
+			name: "non-defaults",
+			item: xbargo.NewMenuItem("Hello").
+				WithDropdown(false).
+				WithEmojize(false).
+				WithShell("say", "hi").
+				WithRefresh(),
+			want: "Hello| emojize=false terminal=false shell='say' param1='hi' dropdown=false refresh=true",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := xbargo.NewPlugin().WithText("Title").WithCleanOutput().WithElements(tc.item)
+			if got, want := render(t, p), "Title|\n---\n"+tc.want+"\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
 //go:embed internal/beaker.png
 var beakerImage []byte
 
@@ -134,3 +1121,257 @@ func ExamplePlugin_imagesAndLinks() {
 	// Icon by Daniel Bruce| refresh=false trim=false
 	// View Source| href=https://iconscout.com/icon/lab-152 refresh=false trim=false
 }
+
+// Example of how to cycle through multiple titles in the menu bar.
+func ExamplePlugin_WithTitles() {
+	xbargo.NewPlugin().WithTitles(
+		xbargo.NewMenuItem("CPU 12%"),
+		xbargo.NewMenuItem("MEM 48%"),
+		xbargo.NewMenuItem("DISK 71%"),
+	).WithElements(
+		xbargo.NewMenuItem("Activity Monitor").WithShell("open", "-a", "Activity Monitor"),
+	).Run()
+	// Output:
+	// CPU 12%| refresh=false trim=false
+	// MEM 48%| refresh=false trim=false
+	// DISK 71%| refresh=false trim=false
+	// ---
+	// Activity Monitor| terminal=false shell='open' param1='-a' param2='Activity Monitor' refresh=false trim=false
+}
+
+func TestMenuItem_WithDropdown(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().WithTitles(
+		xbargo.NewMenuItem("CPU 12%"),
+		xbargo.NewMenuItem("MEM 48%").WithDropdown(false),
+		xbargo.NewMenuItem("DISK 71%").WithDropdown(true),
+	))
+	want := "CPU 12%| refresh=false trim=false\n" +
+		"MEM 48%| dropdown=false refresh=false trim=false\n" +
+		"DISK 71%| dropdown=true refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPlugin_Compact(t *testing.T) {
+	a, b := xbargo.NewMenuItem("a"), xbargo.NewMenuItem("b")
+	hidden := xbargo.NewMenuItem("hidden").When(false)
+	sep := xbargo.Separator{}
+	for _, tc := range []struct {
+		name     string
+		elements []xbargo.XbarElement
+		want     string
+	}{
+		{"unchanged", []xbargo.XbarElement{a, sep, b}, "---\na|\n---\nb|\n"},
+		{"double", []xbargo.XbarElement{a, sep, sep, sep, b}, "---\na|\n---\nb|\n"},
+		{"leading", []xbargo.XbarElement{sep, sep, a, b}, "---\na|\nb|\n"},
+		{"trailing", []xbargo.XbarElement{a, b, sep}, "---\na|\nb|\n"},
+		{"only separators", []xbargo.XbarElement{sep, sep}, ""},
+		{"hidden", []xbargo.XbarElement{a, sep, hidden, sep, b, sep, hidden}, "---\na|\n---\nb|\n"},
+		{"empty", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := (&xbargo.Plugin{Title: xbargo.NewMenuItem("Title"), Elements: tc.elements}).Compact()
+			got := strings.ReplaceAll(render(t, p), " refresh=false trim=false", "")
+			if want := "Title|\n" + tc.want; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_When(t *testing.T) {
+	for _, healthy := range []bool{true, false} {
+		t.Run(fmt.Sprintf("healthy=%t", healthy), func(t *testing.T) {
+			p := xbargo.NewPlugin().WithText("Status").WithElements(
+				xbargo.NewMenuItem("All systems operational").When(healthy),
+				xbargo.NewMenuItem("Checks failing").WithSubMenu(
+					xbargo.NewMenuItem("database: timeout").WithAlt(xbargo.NewMenuItem("Retry")),
+					xbargo.NewMenuItem("cache: ok").When(false),
+				).When(!healthy),
+				xbargo.NewMenuItem("Details").WithAlt(xbargo.NewMenuItem("Debug").When(false)),
+			)
+			want := "Status|\n---\nAll systems operational|\nDetails|\n"
+			if !healthy {
+				want = "Status|\n---\nChecks failing|\n--database: timeout|\n--Retry| alternate=true\nDetails|\n"
+			}
+			if got := strings.ReplaceAll(render(t, p), " refresh=false trim=false", ""); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMenuItem_When_titlesAndElements(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		plugin *xbargo.Plugin
+		want   string
+	}{
+		{
+			name: "hidden cycle title",
+			plugin: xbargo.NewPlugin().WithTitles(
+				xbargo.NewMenuItem("a"),
+				xbargo.NewMenuItem("b").When(false),
+				xbargo.NewMenuItem("c"),
+			),
+			want: "a|\nc|\n",
+		},
+		{
+			name: "all elements hidden",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("a").When(false),
+				xbargo.NewMenuItem("b").When(false),
+			),
+			want: "Title|\n",
+		},
+		{
+			name: "separator before hidden",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.Separator{},
+				xbargo.NewMenuItem("a").When(false),
+			),
+			want: "Title|\n",
+		},
+		{
+			name: "alternative sections",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.NewMenuItem("ok").When(false),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("failures"),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("more").When(false),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("settings"),
+				xbargo.Separator{},
+				xbargo.NewMenuItem("quit").When(false),
+			),
+			want: "Title|\n---\nfailures|\n---\nsettings|\n",
+		},
+		{
+			name: "explicit separators kept",
+			plugin: xbargo.NewPlugin().WithText("Title").WithElements(
+				xbargo.Separator{},
+				xbargo.NewMenuItem("a"),
+				xbargo.Separator{},
+				xbargo.Separator{},
+				xbargo.NewMenuItem("b"),
+			),
+			want: "Title|\n---\n---\na|\n---\n---\nb|\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.plugin.Validate(); err != nil {
+				t.Errorf("unexpected validation error: %v", err)
+			}
+			if got := strings.ReplaceAll(render(t, tc.plugin), " refresh=false trim=false", ""); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterElements(t *testing.T) {
+	a, b := xbargo.NewMenuItem("a"), xbargo.NewMenuItem("b").WithDisabled()
+	got := xbargo.FilterElements([]xbargo.XbarElement{a, xbargo.Separator{}, b}, func(el xbargo.XbarElement) bool {
+		item, ok := el.(*xbargo.MenuItem)
+		return ok && !item.Disabled
+	})
+	if len(got) != 1 || got[0] != a {
+		t.Errorf("got %v, want [a]", got)
+	}
+}
+
+func TestPlugin_WithTitles_withText(t *testing.T) {
+	got := render(t, xbargo.NewPlugin().
+		WithTitles(xbargo.NewMenuItem("first"), xbargo.NewMenuItem("second")).
+		WithText("updated"))
+	want := "updated| refresh=false trim=false\nsecond| refresh=false trim=false\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPlugin_WithTitles_withIcon(t *testing.T) {
+	param := base64.StdEncoding.EncodeToString(beakerImage)
+	for _, tc := range []struct {
+		name   string
+		plugin *xbargo.Plugin
+		want   string
+	}{
+		{
+			name: "template default",
+			plugin: xbargo.NewPlugin().
+				WithTitles(xbargo.NewMenuItem("first"), xbargo.NewMenuItem("second")).
+				WithIcon(bytes.NewReader(beakerImage)),
+			want: "first| templateImage=" + param + " refresh=false trim=false\nsecond| refresh=false trim=false\n",
+		},
+		{
+			name: "own icon",
+			plugin: xbargo.NewPlugin().WithTitles(
+				xbargo.NewMenuItem("first").WithIcon(bytes.NewReader(beakerImage)),
+				xbargo.NewMenuItem("second"),
+			),
+			want: "first| image=" + param + " refresh=false trim=false\nsecond| refresh=false trim=false\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := render(t, tc.plugin); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+type blockingReader struct{ unblock <-chan struct{} }
+
+func (r blockingReader) Read([]byte) (int, error) {
+	<-r.unblock
+	return 0, io.EOF
+}
+
+func TestPlugin_RunWContext(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := xbargo.NewPlugin().WithText("ok").RunWContext(context.Background(), &buf); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := buf.String(), "ok| refresh=false trim=false\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("blocked icon", func(t *testing.T) {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var buf bytes.Buffer
+		start := time.Now()
+		err := xbargo.NewPlugin().WithText("slow").WithElements(
+			xbargo.NewMenuItem("Remote").WithIcon(blockingReader{unblock}),
+		).RunWContext(ctx, &buf)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %s to return", elapsed)
+		}
+		want := "⚠️| refresh=false trim=false\n" +
+			"---\n" +
+			"context deadline exceeded| color=red refresh=false trim=false\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := xbargo.NewPlugin().WithIcon(blockingReader{make(chan struct{})}).RunWContext(ctx, io.Discard)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	})
+}